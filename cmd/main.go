@@ -56,9 +56,10 @@ func entry(conf *config.BaseConf, svrConf *config.ServiceConfig) func() {
 		otelgin.Middleware(svrName),
 		middleware.LoggerWithZap(zap.L()),
 		middleware.RequestHeaders(),
+		middleware.Signature(),
 		middleware.Response(),
 	)
-	router.Init(r)
+	router.Init(r, svrConf)
 	s := &http.Server{
 		Addr:           svrConf.Service.Http.Address,
 		Handler:        r,
@@ -12,10 +12,24 @@ const (
 )
 
 const (
-	UserNotFound  Code = 101
-	CodeExpired   Code = 102
-	WrongCode     Code = 103
-	WrongPassword Code = 104
-	UserFrozen    Code = 105
-	UserBanned    Code = 106
+	UserNotFound    Code = 101
+	CodeExpired     Code = 102
+	WrongCode       Code = 103
+	WrongPassword   Code = 104
+	UserFrozen      Code = 105
+	UserBanned      Code = 106
+	WeakPassword    Code = 107
+	MustChangePwd   Code = 108
+	PasswordExpired Code = 109
+	MustEnrollMFA   Code = 110
+	MFARequired     Code = 111
+)
+
+const (
+	SessionIdleTimeout Code = 411 // 会话空闲超时
+	SessionExpired     Code = 412 // 会话超过最长有效期
+	SessionHijacked    Code = 413 // 会话的客户端指纹与登录时不一致
+	StepUpRequired     Code = 414 // 敏感操作需要重新验证身份
+	IPNotAllowed       Code = 415 // 当前IP不允许访问
+	SignatureInvalid   Code = 416 // API请求签名校验失败
 )
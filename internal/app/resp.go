@@ -27,6 +27,13 @@ func Unauthorized(c *gin.Context, err string) {
 	})
 }
 
+func UnauthorizedCode(c *gin.Context, code codex.Code, err string) {
+	c.AbortWithStatusJSON(http.StatusOK, &APIResponse{
+		Code:    code,
+		Message: err,
+	})
+}
+
 func PermDenied(c *gin.Context) {
 	c.AbortWithStatusJSON(http.StatusOK, &APIResponse{
 		Code:    codex.PermDenied,
@@ -77,3 +84,19 @@ func Failed(c *gin.Context, code codex.Code, format string, a ...any) {
 		Message: fmt.Sprintf(format, a...),
 	})
 }
+
+func FailedData(c *gin.Context, code codex.Code, data any, format string, a ...any) {
+	c.JSON(http.StatusOK, &APIResponse{
+		Code:    code,
+		Data:    data,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+func FailedExtend(c *gin.Context, code codex.Code, extend any, format string, a ...any) {
+	c.JSON(http.StatusOK, &APIResponse{
+		Code:    code,
+		Message: fmt.Sprintf(format, a...),
+		Extend:  extend,
+	})
+}
@@ -5,30 +5,154 @@ import (
 	"admin/internal/middleware"
 	"fmt"
 	"net/http"
+	gopath "path"
+	"slices"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-func Get(r *gin.RouterGroup, path string, h gin.HandlerFunc) {
-	route(r, http.MethodGet, path, "", h)
+type options struct {
+	scopes []auth.Scope
+	stepUp bool
+	public bool
 }
 
-func Post(r *gin.RouterGroup, path string, h gin.HandlerFunc) {
-	route(r, http.MethodPost, path, "", h)
+// Option 路由选项
+type Option func(*options)
+
+// AllowScope 允许受限会话访问该路由
+func AllowScope(scopes ...auth.Scope) Option {
+	return func(o *options) {
+		o.scopes = append(o.scopes, scopes...)
+	}
+}
+
+// StepUp 标记为敏感操作，需要近期重新验证过身份才能访问
+func StepUp() Option {
+	return func(o *options) {
+		o.stepUp = true
+	}
+}
+
+// Public 标记为不需要权限码的路由：未登录即可访问的接口，或任何已登录管理员都可访问的个人接口
+func Public() Option {
+	return func(o *options) {
+		o.public = true
+	}
+}
+
+// routeInfo 路由的认证和权限状态，启动时输出报告
+type routeInfo struct {
+	method string
+	path   string
+	auth   bool
+	perm   auth.PermCode
+	public bool
+	stepUp bool
+	scopes []auth.Scope
+}
+
+// routes 经routerx注册的全部路由
+var routes = make(map[string]*routeInfo)
+
+func Get(r *gin.RouterGroup, path string, h gin.HandlerFunc, opts ...Option) {
+	route(r, http.MethodGet, path, "", h, opts...)
+}
+
+func Post(r *gin.RouterGroup, path string, h gin.HandlerFunc, opts ...Option) {
+	route(r, http.MethodPost, path, "", h, opts...)
 }
 
-func GetPerm(r *gin.RouterGroup, path string, code auth.PermCode, h gin.HandlerFunc) {
-	route(r, http.MethodGet, path, code, h)
+func GetPerm(r *gin.RouterGroup, path string, code auth.PermCode, h gin.HandlerFunc, opts ...Option) {
+	route(r, http.MethodGet, path, code, h, opts...)
 }
 
-func PostPerm(r *gin.RouterGroup, path string, code auth.PermCode, h gin.HandlerFunc) {
-	route(r, http.MethodPost, path, code, h)
+func PostPerm(r *gin.RouterGroup, path string, code auth.PermCode, h gin.HandlerFunc, opts ...Option) {
+	route(r, http.MethodPost, path, code, h, opts...)
 }
 
-func route(r *gin.RouterGroup, method, path string, code auth.PermCode, h gin.HandlerFunc) {
+func route(r *gin.RouterGroup, method, path string, code auth.PermCode, h gin.HandlerFunc, opts ...Option) {
+	o := new(options)
+	for _, opt := range opts {
+		opt(o)
+	}
+	fullPath := joinPath(r.BasePath(), path)
+	key := fmt.Sprintf("%s:%s", method, fullPath)
+	// 默认拒绝：每个路由必须声明权限码或显式标记为Public
+	authed := slices.ContainsFunc(r.Handlers, middleware.IsAuth)
+	switch {
+	case code != "" && o.public:
+		panic(fmt.Sprintf("route %s has both perm %s and Public", key, code))
+	case code != "" && !authed:
+		panic(fmt.Sprintf("route %s uses perm %s but is not behind middleware.Auth", key, code))
+	case code == "" && !o.public:
+		if authed {
+			panic(fmt.Sprintf("route %s requires login but has neither a perm code nor routerx.Public()", key))
+		}
+		panic(fmt.Sprintf("route %s has no authentication, mark it routerx.Public() if intended", key))
+	}
 	if code != "" {
-		key := fmt.Sprintf("%s:%s%s", method, r.BasePath(), path)
+		if !auth.IsValidPerm(code) {
+			panic(fmt.Sprintf("route %s uses perm %s which is not declared in the perm catalog", key, code))
+		}
 		auth.AllRouterPerms[key] = code
 	}
+	routes[key] = &routeInfo{
+		method: method,
+		path:   fullPath,
+		auth:   authed,
+		perm:   code,
+		public: o.public,
+		stepUp: o.stepUp,
+		scopes: o.scopes,
+	}
+	if len(o.scopes) > 0 {
+		auth.AllowScope(key, o.scopes...)
+	}
+	if o.stepUp {
+		auth.RequireStepUp(key)
+	}
 	r.Handle(method, path, middleware.CheckPerm(), h)
 }
+
+// joinPath 与gin拼接路由组路径的方式一致，保证与c.FullPath()相同
+func joinPath(base, path string) string {
+	if path == "" {
+		return base
+	}
+	full := gopath.Join(base, path)
+	if strings.HasSuffix(path, "/") && !strings.HasSuffix(full, "/") {
+		full += "/"
+	}
+	return full
+}
+
+// Report 输出全部路由的认证和权限状态；绕过routerx直接注册的路由没有经过权限检查，启动时拒绝
+func Report(engine *gin.Engine) {
+	var unchecked []string
+	for _, ri := range engine.Routes() {
+		key := fmt.Sprintf("%s:%s", ri.Method, ri.Path)
+		info, ok := routes[key]
+		if !ok {
+			unchecked = append(unchecked, key)
+			continue
+		}
+		scopes := make([]string, 0, len(info.scopes))
+		for _, sc := range info.scopes {
+			scopes = append(scopes, string(sc))
+		}
+		zap.L().Info("route",
+			zap.String("method", info.method),
+			zap.String("path", info.path),
+			zap.Bool("auth", info.auth),
+			zap.String("perm", string(info.perm)),
+			zap.Bool("public", info.public),
+			zap.Bool("step_up", info.stepUp),
+			zap.Strings("scopes", scopes))
+	}
+	if len(unchecked) > 0 {
+		panic(fmt.Sprintf("routes registered without routerx: %s", strings.Join(unchecked, ", ")))
+	}
+}
@@ -0,0 +1,101 @@
+package routerx
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/middleware"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func noop(c *gin.Context) {
+	c.String(http.StatusOK, "ok")
+}
+
+// mustPanic fn必须panic，且信息包含want
+func mustPanic(t *testing.T, want string, fn func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		r := recover()
+		if r == nil {
+			t.Fatalf("expected panic containing %q", want)
+		}
+		if msg, _ := r.(string); !strings.Contains(msg, want) {
+			t.Fatalf("panic = %v, want it to contain %q", r, want)
+		}
+	}()
+	fn()
+}
+
+func TestRouteRejected(t *testing.T) {
+	cases := []struct {
+		name     string
+		register func(open, authed *gin.RouterGroup)
+		want     string
+	}{
+		{"perm without auth", func(open, _ *gin.RouterGroup) {
+			PostPerm(open, "/perm-no-auth", auth.MemberList, noop)
+		}, "is not behind middleware.Auth"},
+		{"auth without perm or public", func(_, authed *gin.RouterGroup) {
+			Post(authed, "/auth-no-perm", noop)
+		}, "has neither a perm code nor routerx.Public()"},
+		{"no auth and not public", func(open, _ *gin.RouterGroup) {
+			Get(open, "/open-not-public", noop)
+		}, "has no authentication"},
+		{"perm and public", func(_, authed *gin.RouterGroup) {
+			PostPerm(authed, "/perm-and-public", auth.MemberList, noop, Public())
+		}, "has both perm"},
+		{"perm not in catalog", func(_, authed *gin.RouterGroup) {
+			PostPerm(authed, "/unknown-perm", auth.PermCode("nope.list"), noop)
+		}, "not declared in the perm catalog"},
+		{"menu code as perm", func(_, authed *gin.RouterGroup) {
+			PostPerm(authed, "/menu-perm", auth.MenuMember, noop)
+		}, "not declared in the perm catalog"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := gin.New()
+			open := engine.Group("/routerx-reject")
+			authed := open.Group("", middleware.Auth())
+			mustPanic(t, tc.want, func() { tc.register(open, authed) })
+		})
+	}
+}
+
+func TestReportRejectsRawRoute(t *testing.T) {
+	engine := gin.New()
+	g := engine.Group("/routerx-report-raw")
+	Get(g, "/ok", noop, Public())
+	engine.GET("/routerx-report-raw/raw", noop)
+	mustPanic(t, "GET:/routerx-report-raw/raw", func() { Report(engine) })
+}
+
+func TestRouteRegistered(t *testing.T) {
+	engine := gin.New()
+	open := engine.Group("/routerx-ok")
+	authed := open.Group("", middleware.Auth())
+	Get(open, "/ping", noop, Public())
+	PostPerm(authed, "/list", auth.MemberList, noop, StepUp())
+	Report(engine)
+
+	if got := auth.AllRouterPerms["POST:/routerx-ok/list"]; got != auth.MemberList {
+		t.Fatalf("registered perm = %q, want %q", got, auth.MemberList)
+	}
+	if !auth.StepUpRouters["POST:/routerx-ok/list"] {
+		t.Fatal("route should require step-up")
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/routerx-ok/ping", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("public route = %d %q, want 200 ok", w.Code, w.Body.String())
+	}
+}
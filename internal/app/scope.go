@@ -0,0 +1,20 @@
+package app
+
+import (
+	"admin/internal/app/codex"
+	"admin/internal/common/auth"
+)
+
+// ScopeCodes 受限会话对应的错误码，登录时和访问其他接口时返回
+var ScopeCodes = map[auth.Scope]codex.Code{
+	auth.ScopeChangePassword:  codex.MustChangePwd,
+	auth.ScopePasswordExpired: codex.PasswordExpired,
+	auth.ScopeMFAEnroll:       codex.MustEnrollMFA,
+}
+
+// ScopeMsgs 受限会话登录时的提示
+var ScopeMsgs = map[auth.Scope]string{
+	auth.ScopeChangePassword:  "请先修改密码",
+	auth.ScopePasswordExpired: "密码已过期，请先修改密码",
+	auth.ScopeMFAEnroll:       "请先绑定谷歌验证器",
+}
@@ -1 +1,315 @@
 package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+	"wallet/common-lib/rdb"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 每个管理员的会话索引: ZSET, member为session ID, score为登录时间
+const sessionIndexPrefix = "admin.sessions:"
+
+// 会话的可变状态: HASH, 与会话同时过期；请求过程中只更新这里，不改写会话内容
+const (
+	sessionStateSuffix = ":state"
+	fieldLastSeenAt    = "last_seen_at"
+	fieldRole          = "role"
+	fieldGen           = "gen"
+	fieldCheckedAt     = "checked_at"
+)
+
+// 会话重新验证身份后可访问敏感操作的截止时间，单独存放并在截止时间过期，不改写会话内容
+const sessionElevatedSuffix = ":elevated"
+
+// 每个管理员的会话代数，管理员状态、角色或密码变更时递增，会话记录的代数落后时需重新与admins表核对
+const sessionGenPrefix = "admin.session.gen:"
+
+// Session 会话索引中的一条记录
+type Session struct {
+	SID string
+	*User
+}
+
+func sessionIndexKey(adminID int64) string {
+	return fmt.Sprintf("%s%d", sessionIndexPrefix, adminID)
+}
+
+func sessionStateKey(sid string) string {
+	return sid + sessionStateSuffix
+}
+
+func sessionElevatedKey(sid string) string {
+	return sid + sessionElevatedSuffix
+}
+
+func sessionGenKey(adminID int64) string {
+	return fmt.Sprintf("%s%d", sessionGenPrefix, adminID)
+}
+
+// SessionTag 会话对外展示的标识，避免把session ID本身下发给前端
+func SessionTag(sid string) string {
+	sum := sha256.Sum256([]byte(sid))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SaveSession 保存会话并写入管理员的会话索引，ttl为会话在Redis中的保留时间
+func SaveSession(ctx context.Context, sid string, u *User, ttl time.Duration) error {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	idx := sessionIndexKey(u.ID)
+	state := sessionStateKey(sid)
+	_, err = rdb.Client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, sid, data, ttl)
+		p.HSet(ctx, state, fieldLastSeenAt, u.LastSeenAt, fieldRole, u.Role, fieldGen, u.Gen, fieldCheckedAt, u.CheckedAt)
+		p.Expire(ctx, state, ttl)
+		p.ZAdd(ctx, idx, redis.Z{Score: float64(u.LoginAt), Member: sid})
+		// 索引的有效期只延长不缩短，保证不早于其中任一会话过期
+		p.ExpireNX(ctx, idx, ttl)
+		p.ExpireGT(ctx, idx, ttl)
+		return nil
+	})
+	return err
+}
+
+// GetSession 读取会话，不存在时返回redis.Nil
+func GetSession(ctx context.Context, sid string) (*User, error) {
+	var (
+		get      *redis.StringCmd
+		state    *redis.MapStringStringCmd
+		elevated *redis.StringCmd
+	)
+	_, err := rdb.Client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		get = p.Get(ctx, sid)
+		state = p.HGetAll(ctx, sessionStateKey(sid))
+		elevated = p.Get(ctx, sessionElevatedKey(sid))
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	data, err := get.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	u := new(User)
+	if err = json.Unmarshal(data, u); err != nil {
+		return nil, err
+	}
+	applyState(u, state.Val())
+	if v, err := elevated.Int64(); err == nil {
+		u.ElevatedUntil = v
+	}
+	return u, nil
+}
+
+// applyState 用会话状态覆盖会话内容中的初始值
+func applyState(u *User, state map[string]string) {
+	if v, err := strconv.ParseInt(state[fieldLastSeenAt], 10, 64); err == nil {
+		u.LastSeenAt = v
+	}
+	if v, err := strconv.Atoi(state[fieldRole]); err == nil {
+		u.Role = v
+	}
+	if v, err := strconv.ParseInt(state[fieldGen], 10, 64); err == nil {
+		u.Gen = v
+	}
+	if v, err := strconv.ParseInt(state[fieldCheckedAt], 10, 64); err == nil {
+		u.CheckedAt = v
+	}
+}
+
+// touchScript 会话仍存在时才记录活跃时间并顺延有效期，避免请求过程中被注销的会话又被写回
+var touchScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+redis.call('HSET', KEYS[2], 'last_seen_at', ARGV[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[1])
+redis.call('PEXPIRE', KEYS[3], ARGV[1], 'GT')
+return 1
+`)
+
+// TouchSession 记录最近活跃时间并顺延会话有效期，会话已被删除时不做任何操作
+func TouchSession(ctx context.Context, sid string, u *User, ttl time.Duration) error {
+	u.LastSeenAt = time.Now().Unix()
+	keys := []string{sid, sessionStateKey(sid), sessionIndexKey(u.ID)}
+	return touchScript.Run(ctx, rdb.Client, keys, ttl.Milliseconds(), u.LastSeenAt).Err()
+}
+
+// elevateScript 会话仍存在时才记录重新验证身份的截止时间
+var elevateScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('SET', KEYS[2], ARGV[1], 'EXAT', ARGV[1])
+return 1
+`)
+
+// ElevateSession 会话在until之前可以访问敏感操作，会话不存在时返回redis.Nil
+func ElevateSession(ctx context.Context, sid string, until time.Time) error {
+	ok, err := elevateScript.Run(ctx, rdb.Client, []string{sid, sessionElevatedKey(sid)}, until.Unix()).Int()
+	if err != nil {
+		return err
+	}
+	if ok == 0 {
+		return redis.Nil
+	}
+	return nil
+}
+
+// SessionGen 管理员当前的会话代数
+func SessionGen(ctx context.Context, adminID int64) (int64, error) {
+	gen, err := rdb.Client.Get(ctx, sessionGenKey(adminID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return gen, err
+}
+
+// BumpSessionGen 递增管理员的会话代数，其全部会话在下一次请求时重新核对
+func BumpSessionGen(ctx context.Context, adminID int64) error {
+	return rdb.Client.Incr(ctx, sessionGenKey(adminID)).Err()
+}
+
+// markCheckedScript 只有会话仍存在且核对时读取的代数仍是最新时才写入，避免并发的旧结果覆盖变更
+var markCheckedScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local gen = tonumber(redis.call('GET', KEYS[3]) or '0')
+if gen ~= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('HSET', KEYS[2], 'role', ARGV[1], 'gen', ARGV[2], 'checked_at', ARGV[3])
+return 1
+`)
+
+// MarkSessionChecked 记录会话与admins表核对的结果（角色、代数和核对时间）
+func MarkSessionChecked(ctx context.Context, sid string, u *User) error {
+	keys := []string{sid, sessionStateKey(sid), sessionGenKey(u.ID)}
+	return markCheckedScript.Run(ctx, rdb.Client, keys, u.Role, u.Gen, u.CheckedAt).Err()
+}
+
+// UpdateSession 覆盖会话内容，保持原有效期
+func UpdateSession(ctx context.Context, sid string, u *User) error {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	err = rdb.Client.SetArgs(ctx, sid, data, redis.SetArgs{KeepTTL: true, Mode: "XX"}).Err()
+	if errors.Is(err, redis.Nil) {
+		// 会话已过期
+		return nil
+	}
+	return err
+}
+
+// ListSessions 列出管理员当前有效的会话（按登录时间升序），顺带清理索引中已过期的记录
+func ListSessions(ctx context.Context, adminID int64) ([]*Session, error) {
+	idx := sessionIndexKey(adminID)
+	sids, err := rdb.Client.ZRange(ctx, idx, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(sids) == 0 {
+		return nil, nil
+	}
+	var (
+		get    *redis.SliceCmd
+		states = make([]*redis.MapStringStringCmd, len(sids))
+	)
+	_, err = rdb.Client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		get = p.MGet(ctx, sids...)
+		for i, sid := range sids {
+			states[i] = p.HGetAll(ctx, sessionStateKey(sid))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	vals := get.Val()
+
+	var (
+		list  = make([]*Session, 0, len(sids))
+		stale = make([]any, 0)
+	)
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			stale = append(stale, sids[i])
+			continue
+		}
+		u := new(User)
+		if err = json.Unmarshal([]byte(s), u); err != nil || u.ID != adminID {
+			stale = append(stale, sids[i])
+			continue
+		}
+		applyState(u, states[i].Val())
+		list = append(list, &Session{SID: sids[i], User: u})
+	}
+	if len(stale) > 0 {
+		_ = rdb.Client.ZRem(ctx, idx, stale...).Err()
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].LoginAt < list[j].LoginAt
+	})
+	return list, nil
+}
+
+// DelSession 删除管理员的单个会话
+func DelSession(ctx context.Context, adminID int64, sid string) error {
+	_, err := rdb.Client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Del(ctx, sid, sessionStateKey(sid), sessionElevatedKey(sid))
+		p.ZRem(ctx, sessionIndexKey(adminID), sid)
+		return nil
+	})
+	return err
+}
+
+// DelSessions 删除管理员除keep以外的全部会话，keep为空时全部删除
+func DelSessions(ctx context.Context, adminID int64, keep string) (int, error) {
+	idx := sessionIndexKey(adminID)
+	sids, err := rdb.Client.ZRange(ctx, idx, 0, -1).Result()
+	if err != nil {
+		return 0, err
+	}
+	del := make([]string, 0, len(sids))
+	for _, sid := range sids {
+		if sid != keep {
+			del = append(del, sid)
+		}
+	}
+	if len(del) == 0 {
+		return 0, nil
+	}
+	var (
+		keys    = make([]string, 0, len(del)*3)
+		members = make([]any, 0, len(del))
+	)
+	for _, sid := range del {
+		keys = append(keys, sid, sessionStateKey(sid), sessionElevatedKey(sid))
+		members = append(members, sid)
+	}
+	_, err = rdb.Client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Del(ctx, keys...)
+		p.ZRem(ctx, idx, members...)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(del), nil
+}
@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"testing"
+	"time"
+	"wallet/common-lib/rdb"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// setupRedis 连接测试用的Redis（ADMIN_TEST_REDIS，默认127.0.0.1:6379），不可用时跳过
+func setupRedis(t *testing.T) context.Context {
+	t.Helper()
+	addr := os.Getenv("ADMIN_TEST_REDIS")
+	if addr == "" {
+		addr = "127.0.0.1:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Skipf("redis %s unavailable: %v", addr, err)
+	}
+	prev := rdb.Client
+	rdb.Client = client
+	t.Cleanup(func() {
+		rdb.Client = prev
+		_ = client.Close()
+	})
+	return ctx
+}
+
+// newTestSession 为随机的管理员ID创建会话，测试结束后清理
+func newTestSession(t *testing.T, ctx context.Context, adminID int64) (string, *User) {
+	t.Helper()
+	sid, err := NewSessionID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	u := &User{ID: adminID, Account: "test", Role: 2, LoginAt: now, LastSeenAt: now, CheckedAt: now}
+	if err = SaveSession(ctx, sid, u, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = rdb.Client.Del(ctx, sid, sessionStateKey(sid), sessionElevatedKey(sid), sessionIndexKey(adminID)).Err()
+	})
+	return sid, u
+}
+
+func testAdminID() int64 {
+	return 1<<40 + rand.Int64N(1<<30)
+}
+
+// assertGone 会话及其状态都已删除，且不在会话索引中
+func assertGone(t *testing.T, ctx context.Context, adminID int64, sid string) {
+	t.Helper()
+	if _, err := GetSession(ctx, sid); !errors.Is(err, redis.Nil) {
+		t.Fatalf("session %s should be gone, got err=%v", SessionTag(sid), err)
+	}
+	if n := rdb.Client.Exists(ctx, sessionStateKey(sid), sessionElevatedKey(sid)).Val(); n != 0 {
+		t.Fatalf("session state of %s should be gone", SessionTag(sid))
+	}
+	list, err := ListSessions(ctx, adminID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range list {
+		if s.SID == sid {
+			t.Fatalf("session %s is still listed", SessionTag(sid))
+		}
+	}
+}
+
+func TestTouchSession(t *testing.T) {
+	ctx := setupRedis(t)
+	adminID := testAdminID()
+	sid, _ := newTestSession(t, ctx, adminID)
+
+	u, err := GetSession(ctx, sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.LastSeenAt = 0
+	if err = TouchSession(ctx, sid, u, 2*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetSession(ctx, sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.LastSeenAt != u.LastSeenAt || got.LastSeenAt == 0 {
+		t.Fatalf("last_seen_at = %d, want %d", got.LastSeenAt, u.LastSeenAt)
+	}
+	if ttl := rdb.Client.TTL(ctx, sid).Val(); ttl <= time.Hour {
+		t.Fatalf("session ttl = %s, want extended to 2h", ttl)
+	}
+}
+
+// 请求进行中会话被注销，请求结束时的TouchSession不能把会话写回
+func TestRevokeDuringRequest(t *testing.T) {
+	cases := []struct {
+		name   string
+		revoke func(ctx context.Context, adminID int64, sid, current string) error
+	}{
+		{"logout or revoke", func(ctx context.Context, adminID int64, sid, _ string) error {
+			return DelSession(ctx, adminID, sid)
+		}},
+		{"revoke others", func(ctx context.Context, adminID int64, _, current string) error {
+			_, err := DelSessions(ctx, adminID, current)
+			return err
+		}},
+		{"force logout", func(ctx context.Context, adminID int64, _, _ string) error {
+			_, err := DelSessions(ctx, adminID, "")
+			return err
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := setupRedis(t)
+			adminID := testAdminID()
+			current, _ := newTestSession(t, ctx, adminID)
+			sid, _ := newTestSession(t, ctx, adminID)
+
+			// 请求开始时读取的会话
+			inFlight, err := GetSession(ctx, sid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = tc.revoke(ctx, adminID, sid, current); err != nil {
+				t.Fatal(err)
+			}
+			// 请求结束
+			if err = TouchSession(ctx, sid, inFlight, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			assertGone(t, ctx, adminID, sid)
+
+			// 注销后仍然可以再次注销全部会话
+			if _, err = DelSessions(ctx, adminID, ""); err != nil {
+				t.Fatal(err)
+			}
+			assertGone(t, ctx, adminID, current)
+		})
+	}
+}
+
+// 管理员变更后，变更前开始核对的请求不能把旧角色写回会话
+func TestMarkSessionCheckedStale(t *testing.T) {
+	ctx := setupRedis(t)
+	adminID := testAdminID()
+	sid, _ := newTestSession(t, ctx, adminID)
+	t.Cleanup(func() {
+		_ = rdb.Client.Del(ctx, sessionGenKey(adminID)).Err()
+	})
+
+	// 请求读取代数后，管理员被降级
+	stale, err := GetSession(ctx, sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = BumpSessionGen(ctx, adminID); err != nil {
+		t.Fatal(err)
+	}
+	stale.Role = SuperAdminRoleID
+	stale.CheckedAt = time.Now().Unix()
+	if err = MarkSessionChecked(ctx, sid, stale); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetSession(ctx, sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen, err := SessionGen(ctx, adminID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Role == SuperAdminRoleID || got.Gen == gen {
+		t.Fatalf("stale check was persisted: role=%d gen=%d current=%d", got.Role, got.Gen, gen)
+	}
+
+	// 使用最新代数的核对结果可以写入
+	got.Role = 3
+	got.Gen = gen
+	if err = MarkSessionChecked(ctx, sid, got); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = GetSession(ctx, sid); err != nil {
+		t.Fatal(err)
+	}
+	if got.Role != 3 || got.Gen != gen {
+		t.Fatalf("check not persisted: role=%d gen=%d", got.Role, got.Gen)
+	}
+}
+
+// 请求进行中会话完成了重新验证，请求结束时的写入不能丢掉重新验证的结果
+func TestElevateDuringRequest(t *testing.T) {
+	ctx := setupRedis(t)
+	adminID := testAdminID()
+	sid, _ := newTestSession(t, ctx, adminID)
+
+	inFlight, err := GetSession(ctx, sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	until := time.Now().Add(10 * time.Minute).Truncate(time.Second)
+	if err = ElevateSession(ctx, sid, until); err != nil {
+		t.Fatal(err)
+	}
+	// 请求结束，以及并发的会话内容更新
+	if err = TouchSession(ctx, sid, inFlight, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err = UpdateSession(ctx, sid, inFlight); err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetSession(ctx, sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ElevatedUntil != until.Unix() {
+		t.Fatalf("elevated_until = %d, want %d", got.ElevatedUntil, until.Unix())
+	}
+
+	if err = DelSession(ctx, adminID, sid); err != nil {
+		t.Fatal(err)
+	}
+	assertGone(t, ctx, adminID, sid)
+	// 已注销的会话不能再重新验证
+	if err = ElevateSession(ctx, sid, until); !errors.Is(err, redis.Nil) {
+		t.Fatalf("elevate revoked session: err=%v, want redis.Nil", err)
+	}
+	assertGone(t, ctx, adminID, sid)
+}
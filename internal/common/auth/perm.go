@@ -1,23 +1,338 @@
 package auth
 
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
 type PermCode string
 
+// 菜单，不能单独授予，拥有其下任一权限时显示；菜单码同时是其下权限码的前缀，如 member.* 授予会员管理下的全部权限
+const (
+	MenuMember   PermCode = "member"
+	MenuAdmin    PermCode = "admin"
+	MenuRole     PermCode = "role"
+	MenuSecurity PermCode = "security"
+	MenuAudit    PermCode = "audit"
+	MenuAgent    PermCode = "agent"
+)
+
+// 权限码以.分隔层级，授予时可用通配符，如 security.* 或 *
+const (
+	MemberList       PermCode = "member.list"
+	MemberListExport PermCode = "member.list.export"
+	AdminForceLogout PermCode = "admin.force-logout"
+	AdminUnlockLogin PermCode = "admin.unlock-login"
+	AdminList        PermCode = "admin.list"
+	AdminDetail      PermCode = "admin.detail"
+	AdminEditRole    PermCode = "admin.role.edit"
+	AdminEnable      PermCode = "admin.enable"
+	AdminDisable     PermCode = "admin.disable"
+	AdminDelete      PermCode = "admin.delete"
+	RoleCreate       PermCode = "role.create"
+	RoleRename       PermCode = "role.rename"
+	RoleDelete       PermCode = "role.delete"
+	AdminLoginLogs   PermCode = "admin.login-logs"
+	APITokenList     PermCode = "security.api-token.list"
+	APITokenCreate   PermCode = "security.api-token.create"
+	APITokenRevoke   PermCode = "security.api-token.revoke"
+	AllowlistList    PermCode = "security.allowlist.list"
+	AllowlistCreate  PermCode = "security.allowlist.create"
+	AllowlistUpdate  PermCode = "security.allowlist.update"
+	AllowlistDelete  PermCode = "security.allowlist.delete"
+	IPRuleList       PermCode = "security.ip-rule.list"
+	IPRuleCreate     PermCode = "security.ip-rule.create"
+	IPRuleUpdate     PermCode = "security.ip-rule.update"
+	IPRuleDelete     PermCode = "security.ip-rule.delete"
+	AuditLogList     PermCode = "audit.log.list"
+
+	AdminCreate        PermCode = "admin.create"
+	AdminResetMFA      PermCode = "admin.mfa.reset"
+	AdminUnbindMFA     PermCode = "admin.mfa.unbind"
+	AdminResetPassword PermCode = "admin.password.reset"
+	AdminPermView      PermCode = "admin.perm.view"
+	AdminPermEdit      PermCode = "admin.perm.edit"
+	RolePermView       PermCode = "role.perm.view"
+	RolePermEdit       PermCode = "role.perm.edit"
+	AgentReviewList    PermCode = "agent.review.list"
+	AgentReviewApprove PermCode = "agent.review.approve"
+	AgentReviewReject  PermCode = "agent.review.reject"
+)
+
+const (
+	PermWildcard = "*"  // 全部权限，超级管理员固定拥有
+	permAnyChild = ".*" // 某一层级下的全部权限
+)
+
+// legacyPermCodes 旧版权限码到新权限码的映射，读取已保存的权限和接收请求时自动转换
+var legacyPermCodes = map[string]PermCode{
+	"member-list":        MemberList,
+	"member-list-export": MemberListExport,
+}
+
+type PermKind string
+
 const (
-	MemberList       PermCode = "member-list"
-	MemberListExport PermCode = "member-list-export"
+	PermKindMenu   PermKind = "menu"
+	PermKindAction PermKind = "action"
 )
 
+// 显示名称的语言
+const (
+	LangZh = "zh-CN"
+	LangEn = "en"
+)
+
+// PermMeta 权限码的展示信息
+type PermMeta struct {
+	Code        PermCode          `json:"code"`
+	Kind        PermKind          `json:"kind"`
+	Name        string            `json:"name"`             // 默认显示名称，与中文标签一致
+	Description string            `json:"description"`      // 说明
+	Group       string            `json:"group"`            // 所属模块
+	Parent      PermCode          `json:"parent,omitempty"` // 上级菜单或权限
+	Sort        int               `json:"sort"`             // 同级排序，升序
+	Labels      map[string]string `json:"labels"`           // 各语言的显示名称
+}
+
+func menu(code PermCode, group string, sort int, zh, en string) PermMeta {
+	return PermMeta{
+		Code:   code,
+		Kind:   PermKindMenu,
+		Name:   zh,
+		Group:  group,
+		Sort:   sort,
+		Labels: map[string]string{LangZh: zh, LangEn: en},
+	}
+}
+
+func action(code, parent PermCode, sort int, zh, en, desc string) PermMeta {
+	return PermMeta{
+		Code:        code,
+		Kind:        PermKindAction,
+		Name:        zh,
+		Description: desc,
+		Parent:      parent,
+		Sort:        sort,
+		Labels:      map[string]string{LangZh: zh, LangEn: en},
+	}
+}
+
+// permMetas 权限目录，新增权限码时在这里声明，动作权限的模块继承自所属菜单
+var permMetas = []PermMeta{
+	menu(MenuMember, "member", 10, "会员管理", "Members"),
+	action(MemberList, MenuMember, 10, "会员列表", "Member list", "查询会员列表"),
+	action(MemberListExport, MemberList, 20, "导出会员列表", "Export members", "导出会员列表"),
+
+	menu(MenuAdmin, "admin", 20, "管理员管理", "Administrators"),
+	action(AdminList, MenuAdmin, 10, "管理员列表", "Admin list", "查询管理员列表"),
+	action(AdminCreate, MenuAdmin, 15, "新增管理员", "Create admin", "新增管理员或服务账号"),
+	action(AdminDetail, MenuAdmin, 20, "管理员详情", "Admin detail", "查看管理员详情、会话数和二次验证状态"),
+	action(AdminEditRole, MenuAdmin, 30, "修改角色", "Change role", "修改管理员的角色"),
+	action(AdminEnable, MenuAdmin, 40, "启用管理员", "Enable admin", "启用被禁用的管理员"),
+	action(AdminDisable, MenuAdmin, 50, "禁用管理员", "Disable admin", "禁用管理员，其会话立即失效"),
+	action(AdminDelete, MenuAdmin, 60, "删除管理员", "Delete admin", "删除管理员，其会话立即失效"),
+	action(AdminForceLogout, MenuAdmin, 70, "强制下线", "Force logout", "踢掉管理员的全部会话"),
+	action(AdminUnlockLogin, MenuAdmin, 80, "解除登录锁定", "Unlock login", "解除账号或IP的登录失败锁定，仅超级管理员可操作"),
+	action(AdminLoginLogs, MenuAdmin, 90, "登录记录", "Login history", "查询管理员的登录记录"),
+	action(AdminResetMFA, MenuAdmin, 100, "重置二次验证", "Reset MFA", "重置管理员的二次验证，仅超级管理员可操作"),
+	action(AdminUnbindMFA, MenuAdmin, 105, "解绑谷歌验证器", "Unbind authenticator", "解绑管理员的谷歌验证器，仅超级管理员可操作"),
+	action(AdminResetPassword, MenuAdmin, 110, "重置密码", "Reset password", "重置管理员的密码，仅超级管理员可操作"),
+	action(AdminPermView, MenuAdmin, 120, "查看管理员权限", "View admin permissions", "查看管理员的角色权限、额外授予和排除"),
+	action(AdminPermEdit, AdminPermView, 10, "修改管理员权限", "Edit admin permissions", "设置管理员的额外授予和排除"),
+
+	menu(MenuRole, "role", 30, "角色管理", "Roles"),
+	action(RoleCreate, MenuRole, 10, "新增角色", "Create role", "新增角色"),
+	action(RoleRename, MenuRole, 20, "修改角色名称", "Rename role", "修改角色名称"),
+	action(RoleDelete, MenuRole, 30, "删除角色", "Delete role", "删除角色，角色下的管理员需转移到其他角色"),
+	action(RolePermView, MenuRole, 40, "查看角色权限", "View role permissions", "查看角色的权限模板"),
+	action(RolePermEdit, RolePermView, 10, "修改角色权限", "Edit role permissions", "修改角色的权限模板，仅超级管理员可操作"),
+
+	menu(MenuSecurity, "security", 40, "安全设置", "Security"),
+	action(AllowlistList, MenuSecurity, 10, "登录IP白名单", "IP allowlist", "查询登录IP白名单"),
+	action(AllowlistCreate, AllowlistList, 10, "新增白名单", "Add allowlist entry", "新增登录IP白名单"),
+	action(AllowlistUpdate, AllowlistList, 20, "修改白名单", "Edit allowlist entry", "修改登录IP白名单"),
+	action(AllowlistDelete, AllowlistList, 30, "删除白名单", "Delete allowlist entry", "删除登录IP白名单"),
+	action(IPRuleList, MenuSecurity, 20, "IP限制规则", "IP rules", "查询角色和管理员的IP限制规则"),
+	action(IPRuleCreate, IPRuleList, 10, "新增IP规则", "Add IP rule", "新增角色或管理员的IP限制规则"),
+	action(IPRuleUpdate, IPRuleList, 20, "修改IP规则", "Edit IP rule", "修改IP限制规则"),
+	action(IPRuleDelete, IPRuleList, 30, "删除IP规则", "Delete IP rule", "删除IP限制规则"),
+	action(APITokenList, MenuSecurity, 30, "API令牌", "API tokens", "查询服务账号的API令牌"),
+	action(APITokenCreate, APITokenList, 10, "创建API令牌", "Create API token", "为服务账号创建API令牌"),
+	action(APITokenRevoke, APITokenList, 20, "撤销API令牌", "Revoke API token", "撤销API令牌"),
+
+	menu(MenuAudit, "audit", 50, "审计日志", "Audit log"),
+	action(AuditLogList, MenuAudit, 10, "审计日志", "Audit log", "查询管理操作审计日志"),
+
+	menu(MenuAgent, "agent", 60, "代理审核", "Agent review"),
+	action(AgentReviewList, MenuAgent, 10, "审核列表", "Review list", "查询待审核的代理申请"),
+	action(AgentReviewApprove, AgentReviewList, 10, "审核通过", "Approve", "通过代理申请"),
+	action(AgentReviewReject, AgentReviewList, 20, "审核拒绝", "Reject", "拒绝代理申请"),
+}
+
+var permCatalog = make(map[PermCode]*PermMeta, len(permMetas))
+
+func init() {
+	for i := range permMetas {
+		m := &permMetas[i]
+		if _, ok := permCatalog[m.Code]; ok {
+			panic(fmt.Sprintf("duplicate perm code: %s", m.Code))
+		}
+		permCatalog[m.Code] = m
+	}
+	for _, m := range permMetas {
+		if m.Kind == PermKindMenu {
+			continue
+		}
+		parent, ok := permCatalog[m.Parent]
+		if !ok {
+			panic(fmt.Sprintf("perm %s has unknown parent %s", m.Code, m.Parent))
+		}
+		// 沿上级找到所属菜单的模块
+		for parent.Kind != PermKindMenu {
+			parent = permCatalog[parent.Parent]
+		}
+		permCatalog[m.Code].Group = parent.Group
+	}
+}
+
 var AllRouterPerms = make(map[string]PermCode)
 
+// IsValidPerm 是否为可授予的权限：具体的权限码（菜单不能单独授予）、*，或至少覆盖一个权限码的通配符如 member.*
 func IsValidPerm(perm PermCode) bool {
-	for _, p := range AllRouterPerms {
-		if p == perm {
+	if m, ok := permCatalog[perm]; ok {
+		return m.Kind == PermKindAction
+	}
+	if perm == PermWildcard {
+		return true
+	}
+	if !strings.HasSuffix(string(perm), permAnyChild) {
+		return false
+	}
+	for _, m := range permMetas {
+		if m.Kind == PermKindAction && PermCovers(string(perm), string(m.Code)) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizePerm 把旧版权限码转换为新权限码，其余原样返回
+func NormalizePerm(perm string) string {
+	if p, ok := legacyPermCodes[perm]; ok {
+		return string(p)
+	}
+	return perm
+}
+
+// PermCovers 授予的权限grant是否覆盖code，code也可以是通配符（用于判断能否转授）。
+// 通配符*覆盖全部；member.* 覆盖 member.list、member.list.export 和 member.list.*；member.list 只覆盖自身
+func PermCovers(grant, code string) bool {
+	if grant == code || grant == PermWildcard {
+		return true
+	}
+	if !strings.HasSuffix(grant, permAnyChild) {
+		return false
+	}
+	return strings.HasPrefix(code, strings.TrimSuffix(grant, "*"))
+}
+
+// PermSet 管理员的有效权限，拒绝优先于授予
+type PermSet struct {
+	Grants []string `json:"grants"`
+	Denies []string `json:"denies"`
+}
+
+// Allows 是否拥有权限code
+func (s *PermSet) Allows(code string) bool {
+	for _, d := range s.Denies {
+		if PermCovers(d, code) {
+			return false
+		}
+	}
+	for _, g := range s.Grants {
+		if PermCovers(g, code) {
 			return true
 		}
 	}
 	return false
 }
 
+// CanGrant 能否把权限perm（可以是通配符）转授给他人：须被自己的授予覆盖，且与自己的拒绝没有交集
+func (s *PermSet) CanGrant(perm string) bool {
+	for _, d := range s.Denies {
+		if PermCovers(d, perm) || PermCovers(perm, d) {
+			return false
+		}
+	}
+	for _, g := range s.Grants {
+		if PermCovers(g, perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand 展开为具体的权限码，供前端按权限码显示按钮
+func (s *PermSet) Expand() []string {
+	list := make([]string, 0)
+	for _, m := range permMetas {
+		if m.Kind == PermKindAction && s.Allows(string(m.Code)) {
+			list = append(list, string(m.Code))
+		}
+	}
+	return list
+}
+
 func GetAllPerms() map[string]PermCode {
 	return AllRouterPerms
 }
+
+// GetPermMeta 查询权限码的展示信息
+func GetPermMeta(perm PermCode) (*PermMeta, bool) {
+	m, ok := permCatalog[perm]
+	return m, ok
+}
+
+// PermCatalog 全部权限码的展示信息，按声明顺序返回
+func PermCatalog() []*PermMeta {
+	list := make([]*PermMeta, 0, len(permMetas))
+	for i := range permMetas {
+		list = append(list, &permMetas[i])
+	}
+	return list
+}
+
+// PermNode 权限树节点
+type PermNode struct {
+	*PermMeta
+	Children []*PermNode `json:"children,omitempty"`
+}
+
+// PermTree 构建权限树，allowed为nil时返回完整的树；否则只保留允许的权限，以及其下有允许的权限的菜单
+func PermTree(allowed func(PermCode) bool) []*PermNode {
+	children := make(map[PermCode][]*PermMeta)
+	for i := range permMetas {
+		m := &permMetas[i]
+		children[m.Parent] = append(children[m.Parent], m)
+	}
+	var build func(parent PermCode) []*PermNode
+	build = func(parent PermCode) []*PermNode {
+		list := children[parent]
+		slices.SortStableFunc(list, func(a, b *PermMeta) int {
+			return cmp.Compare(a.Sort, b.Sort)
+		})
+		nodes := make([]*PermNode, 0, len(list))
+		for _, m := range list {
+			n := &PermNode{PermMeta: m, Children: build(m.Code)}
+			if allowed != nil && len(n.Children) == 0 && (m.Kind == PermKindMenu || !allowed(m.Code)) {
+				continue
+			}
+			nodes = append(nodes, n)
+		}
+		return nodes
+	}
+	return build("")
+}
@@ -0,0 +1,117 @@
+package auth
+
+import "testing"
+
+func TestPermCovers(t *testing.T) {
+	cases := []struct {
+		grant, code string
+		want        bool
+	}{
+		{"*", "member", true},
+		{"*", "member.list.export", true},
+		{"*", "admin.*", true},
+		{"member.*", "member", false},
+		{"member.*", "member.list", true},
+		{"member.*", "member.list.export", true},
+		{"member.*", "member.list.*", true},
+		{"member.*", "memberx.list", false},
+		{"member.*", "*", false},
+		{"member.list", "member.list", true},
+		{"member.list", "member.list.export", false},
+		{"member.list.*", "member.list", false},
+		{"member.list.*", "member.list.export", true},
+		{"member.list.export", "member.*", false},
+	}
+	for _, tc := range cases {
+		if got := PermCovers(tc.grant, tc.code); got != tc.want {
+			t.Errorf("PermCovers(%q, %q) = %v, want %v", tc.grant, tc.code, got, tc.want)
+		}
+	}
+}
+
+func TestPermSetAllows(t *testing.T) {
+	cases := []struct {
+		name string
+		set  PermSet
+		code string
+		want bool
+	}{
+		{"wildcard", PermSet{Grants: []string{"*"}}, "admin.delete", true},
+		{"no grant", PermSet{}, "member.list", false},
+		{"module grant", PermSet{Grants: []string{"member.*"}}, "member.list.export", true},
+		{"other module", PermSet{Grants: []string{"member.*"}}, "memberx.list", false},
+		{"deny over grant", PermSet{Grants: []string{"member.*"}, Denies: []string{"member.list.export"}}, "member.list.export", false},
+		{"deny leaves siblings", PermSet{Grants: []string{"member.*"}, Denies: []string{"member.list.export"}}, "member.list", true},
+		{"deny wildcard over exact grant", PermSet{Grants: []string{"admin.delete"}, Denies: []string{"admin.*"}}, "admin.delete", false},
+		{"deny over super wildcard", PermSet{Grants: []string{"*"}, Denies: []string{"admin.*"}}, "admin.list", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.set.Allows(tc.code); got != tc.want {
+				t.Fatalf("Allows(%q) = %v, want %v", tc.code, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPermSetCanGrant(t *testing.T) {
+	set := &PermSet{
+		Grants: []string{"member.*", "admin.list"},
+		Denies: []string{"member.list.export"},
+	}
+	cases := []struct {
+		perm string
+		want bool
+	}{
+		{"member.list", true},
+		{"admin.list", true},
+		{"admin.delete", false},
+		{"*", false},
+		// 与拒绝重叠：授予被拒绝覆盖，或授予覆盖了拒绝
+		{"member.list.export", false},
+		{"member.list.*", false},
+		{"member.*", false},
+	}
+	for _, tc := range cases {
+		if got := set.CanGrant(tc.perm); got != tc.want {
+			t.Errorf("CanGrant(%q) = %v, want %v", tc.perm, got, tc.want)
+		}
+	}
+}
+
+func TestIsValidPerm(t *testing.T) {
+	cases := []struct {
+		perm PermCode
+		want bool
+	}{
+		{MemberList, true},
+		{MemberListExport, true},
+		{PermWildcard, true},
+		{"member.*", true},
+		{"member.list.*", true},
+		{MenuMember, false},
+		{"nope.*", false},
+		{"memberx.list", false},
+		{"member-list", false},
+		{"", false},
+	}
+	for _, tc := range cases {
+		if got := IsValidPerm(tc.perm); got != tc.want {
+			t.Errorf("IsValidPerm(%q) = %v, want %v", tc.perm, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizePerm(t *testing.T) {
+	cases := map[string]string{
+		"member-list":        string(MemberList),
+		"member-list-export": string(MemberListExport),
+		"member.list":        "member.list",
+		"admin-list":         "admin-list",
+	}
+	for in, want := range cases {
+		if got := NormalizePerm(in); got != want {
+			t.Errorf("NormalizePerm(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
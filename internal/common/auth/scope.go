@@ -0,0 +1,31 @@
+package auth
+
+// Scope 受限会话的作用域，空表示完整会话
+type Scope string
+
+const (
+	ScopeChangePassword  Scope = "change_password"  // 密码被重置，仅允许修改密码
+	ScopePasswordExpired Scope = "password_expired" // 密码已过期（宽限期内），仅允许修改密码
+	ScopeMFAEnroll       Scope = "mfa_enroll"       // 角色要求绑定谷歌验证器，仅允许绑定
+)
+
+// ScopeRouters 受限会话可访问的路由，key为"METHOD:path"
+var ScopeRouters = make(map[Scope]map[string]bool)
+
+// AllowScope 允许指定作用域的受限会话访问路由
+func AllowScope(key string, scopes ...Scope) {
+	for _, s := range scopes {
+		if ScopeRouters[s] == nil {
+			ScopeRouters[s] = make(map[string]bool)
+		}
+		ScopeRouters[s][key] = true
+	}
+}
+
+// ScopeAllows 受限会话是否可以访问路由
+func ScopeAllows(scope Scope, key string) bool {
+	if scope == "" {
+		return true
+	}
+	return ScopeRouters[scope][key]
+}
@@ -9,11 +9,20 @@ import (
 )
 
 type User struct {
-	ID      int64  `json:"id"`
-	Account string `json:"account"`
-	Role    int    `json:"role"`
-	LoginIP string `json:"login_ip,omitempty"`
-	LoginAt int64  `json:"login_at"`
+	ID         int64  `json:"id"`
+	Account    string `json:"account"`
+	Role       int    `json:"role"`
+	LoginIP    string `json:"login_ip,omitempty"`
+	LoginAt    int64  `json:"login_at"`
+	UserAgent  string `json:"user_agent,omitempty"`
+	LastSeenAt int64  `json:"last_seen_at"`
+	CheckedAt  int64  `json:"checked_at"`        // 最近一次与admins表核对的时间
+	PwdSum     string `json:"pwd_sum,omitempty"` // 登录时密码哈希的摘要，密码变更后会话失效
+	UAHash     string `json:"ua_hash,omitempty"` // 登录时User-Agent的摘要，用于会话绑定
+	Scope      Scope  `json:"scope,omitempty"`   // 受限会话的作用域
+	Gen        int64  `json:"-"`                 // 最近一次核对时管理员的会话代数
+
+	ElevatedUntil int64 `json:"-"` // 重新验证身份后可访问敏感操作的截止时间
 }
 
 const (
@@ -21,7 +30,8 @@ const (
 	ReqAdminID        = "adminID"
 	ReqAdminAccount   = "userAccount"
 	ReqRoleID         = "roleID"
-	SessionExpireTime = 3 * 24 * time.Hour
+	SessionExpireTime = 3 * 24 * time.Hour // 默认空闲超时
+	SessionLifetime   = 7 * 24 * time.Hour // 默认最长有效期
 
 	SuperAdminRoleID = 1 // super_admin角色的ID（对应roles表中的第一条记录）
 )
@@ -0,0 +1,16 @@
+package auth
+
+import "time"
+
+// StepUpRouters 需要重新验证身份的敏感路由，key为"METHOD:path"
+var StepUpRouters = make(map[string]bool)
+
+// RequireStepUp 标记路由为敏感操作
+func RequireStepUp(key string) {
+	StepUpRouters[key] = true
+}
+
+// NeedsStepUp 会话访问路由前是否需要重新验证身份
+func NeedsStepUp(u *User, key string, now time.Time) bool {
+	return StepUpRouters[key] && u.ElevatedUntil <= now.Unix()
+}
@@ -0,0 +1,29 @@
+package auth
+
+import (
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	APITokenHeader = "X-Api-Token"
+	ReqTokenScopes = "tokenScopes"
+	ReqAPIToken    = "apiToken" // 签名校验时已读取的令牌记录，认证时直接使用
+)
+
+func GetAPIToken(c *gin.Context) string {
+	return c.GetHeader(APITokenHeader)
+}
+
+// TokenAllows 通过API令牌访问时，权限还须在令牌的作用范围内；会话访问不受限制
+func TokenAllows(c *gin.Context, perm PermCode) bool {
+	v, ok := c.Get(ReqTokenScopes)
+	if !ok {
+		return true
+	}
+	scopes, _ := v.([]string)
+	return slices.ContainsFunc(scopes, func(scope string) bool {
+		return PermCovers(scope, string(perm))
+	})
+}
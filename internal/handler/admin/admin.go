@@ -0,0 +1,186 @@
+package admin
+
+import (
+	adminApp "admin/internal/app"
+	"admin/internal/app/codex"
+	"admin/internal/common/auth"
+	"admin/internal/service/admin_service"
+	"errors"
+	"wallet/common-lib/app"
+	"wallet/common-lib/config"
+	"wallet/common-lib/zapx"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 谷歌验证器中显示的发行方
+const mfaIssuer = "admin"
+
+var svrConf *config.ServiceConfig
+
+// Init 注入服务配置
+func Init(conf *config.ServiceConfig) {
+	svrConf = conf
+}
+
+func Login(c *gin.Context) {
+	req := new(admin_service.LoginReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	resp, err := admin_service.Login(c.Request.Context(), req, c.ClientIP(), c.Request.UserAgent(), svrConf)
+	if err != nil {
+		authFailed(c, err)
+		return
+	}
+	if code, ok := adminApp.ScopeCodes[resp.Scope]; ok {
+		adminApp.FailedData(c, code, resp, "%s", adminApp.ScopeMsgs[resp.Scope])
+		return
+	}
+	app.Result(c, resp)
+}
+
+// authFailed 返回登录/身份验证失败，锁定状态和可用的二次验证方式随响应返回
+func authFailed(c *gin.Context, err error) {
+	var loginErr *admin_service.LoginError
+	if errors.As(err, &loginErr) {
+		adminApp.FailedExtend(c, loginErr.Code, loginErr, "%s", loginErr.Msg)
+		return
+	}
+	var mfaErr *admin_service.MFARequiredError
+	if errors.As(err, &mfaErr) {
+		adminApp.FailedData(c, codex.MFARequired, mfaErr, "%s", mfaErr.Error())
+		return
+	}
+	app.InternalError(c, "%s", err.Error())
+}
+
+// LoginLogs 管理员登录记录
+func LoginLogs(c *gin.Context) {
+	req := new(admin_service.LoginLogsReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	list, total, err := admin_service.LoginLogs(c.Request.Context(), req)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.ResultPage(c, list, total)
+}
+
+func GetRoles(c *gin.Context) {
+	resp, err := admin_service.GetRoles(c.Request.Context())
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, resp)
+}
+
+func CreateAdmin(c *gin.Context) {
+	req := new(admin_service.CreateAdminReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	req.OperatorID = auth.AdminID(c)
+	if err := admin_service.CreateAdmin(c.Request.Context(), req); err != nil {
+		var weakErr *admin_service.WeakPasswordError
+		if errors.As(err, &weakErr) {
+			adminApp.Failed(c, codex.WeakPassword, "%s", weakErr.Msg)
+			return
+		}
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
+
+func GenerateMFASecret(c *gin.Context) {
+	resp, err := admin_service.GenerateMFASecret(c.Request.Context(), auth.AdminID(c), mfaIssuer)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, resp)
+}
+
+func BindMFA(c *gin.Context) {
+	req := new(admin_service.BindMFAReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	resp, err := admin_service.BindMFA(c.Request.Context(), auth.AdminID(c), req)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, resp)
+}
+
+func RegenerateRecoveryCodes(c *gin.Context) {
+	req := new(admin_service.RegenerateRecoveryCodesReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	resp, err := admin_service.RegenerateRecoveryCodes(c.Request.Context(), auth.AdminID(c), c.ClientIP(), req)
+	if err != nil {
+		authFailed(c, err)
+		return
+	}
+	app.Result(c, resp)
+}
+
+func UnbindMFA(c *gin.Context) {
+	req := new(admin_service.UnbindMFAReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if req.TargetUserID <= 0 {
+		app.InvalidParams(c, "empty target user ID")
+		return
+	}
+	req.OperatorID = auth.AdminID(c)
+	if err := admin_service.UnbindMFA(c.Request.Context(), req); err != nil {
+		zapx.ErrorCtx(c.Request.Context(), "unbind mfa error", zap.Error(err))
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
+
+func ResetMFA(c *gin.Context) {
+	req := new(admin_service.ResetMFAReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	req.OperatorID = auth.AdminID(c)
+	resp, err := admin_service.ResetMFA(c.Request.Context(), req)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, resp)
+}
+
+func UnlockLogin(c *gin.Context) {
+	req := new(admin_service.UnlockLoginReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	req.OperatorID = auth.AdminID(c)
+	if err := admin_service.UnlockLogin(c.Request.Context(), req); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/service/audit_service"
+	"admin/internal/service/ip_service"
+	"wallet/common-lib/app"
+
+	"github.com/gin-gonic/gin"
+)
+
+// operator 当前操作者，用于审计日志
+func operator(c *gin.Context) *audit_service.Operator {
+	return &audit_service.Operator{
+		ID:      auth.AdminID(c),
+		Account: auth.AdminAccount(c),
+		RoleID:  auth.AdminRole(c),
+		IP:      c.ClientIP(),
+	}
+}
+
+// AllowlistList 登录IP白名单列表
+func AllowlistList(c *gin.Context) {
+	list, err := ip_service.ListAllowlist(c.Request.Context())
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, gin.H{
+		"list": list,
+	})
+}
+
+// AllowlistCreate 新增登录IP白名单
+func AllowlistCreate(c *gin.Context) {
+	req := new(ip_service.AllowlistReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if err := ip_service.CreateAllowlist(c.Request.Context(), operator(c), req); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
+
+// AllowlistUpdate 修改登录IP白名单
+func AllowlistUpdate(c *gin.Context) {
+	req := new(ip_service.AllowlistReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if req.ID <= 0 {
+		app.InvalidParams(c, "empty ID")
+		return
+	}
+	if err := ip_service.UpdateAllowlist(c.Request.Context(), operator(c), req); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
+
+type AllowlistDeleteReq struct {
+	ID int64 `json:"id" binding:"required"`
+}
+
+// AllowlistDelete 删除登录IP白名单
+func AllowlistDelete(c *gin.Context) {
+	req := new(AllowlistDeleteReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if err := ip_service.DeleteAllowlist(c.Request.Context(), operator(c), req.ID); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
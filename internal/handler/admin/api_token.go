@@ -0,0 +1,58 @@
+package admin
+
+import (
+	"admin/internal/service/token_service"
+	"wallet/common-lib/app"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APITokenList 服务账号API令牌列表
+func APITokenList(c *gin.Context) {
+	req := new(token_service.ListReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	list, err := token_service.List(c.Request.Context(), req)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, gin.H{
+		"list": list,
+	})
+}
+
+// APITokenCreate 为服务账号创建API令牌，令牌明文只返回一次
+func APITokenCreate(c *gin.Context) {
+	req := new(token_service.CreateReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	resp, err := token_service.Create(c.Request.Context(), operator(c), req)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, resp)
+}
+
+type APITokenRevokeReq struct {
+	ID int64 `json:"id" binding:"required"`
+}
+
+// APITokenRevoke 撤销API令牌
+func APITokenRevoke(c *gin.Context) {
+	req := new(APITokenRevokeReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if err := token_service.Revoke(c.Request.Context(), operator(c), req.ID); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
@@ -0,0 +1,23 @@
+package admin
+
+import (
+	"admin/internal/service/audit_service"
+	"wallet/common-lib/app"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditLogs 管理操作审计日志
+func AuditLogs(c *gin.Context) {
+	req := new(audit_service.ListReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	list, total, err := audit_service.List(c.Request.Context(), req)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.ResultPage(c, list, total)
+}
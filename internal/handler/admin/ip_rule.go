@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"admin/internal/service/ip_service"
+	"wallet/common-lib/app"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IPRuleList 角色/管理员IP限制规则列表
+func IPRuleList(c *gin.Context) {
+	req := new(ip_service.ListRulesReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	list, err := ip_service.ListRules(c.Request.Context(), req)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, gin.H{
+		"list": list,
+	})
+}
+
+// IPRuleCreate 新增IP限制规则
+func IPRuleCreate(c *gin.Context) {
+	req := new(ip_service.RuleReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if err := ip_service.CreateRule(c.Request.Context(), operator(c), req); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
+
+// IPRuleUpdate 修改IP限制规则
+func IPRuleUpdate(c *gin.Context) {
+	req := new(ip_service.RuleReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if req.ID <= 0 {
+		app.InvalidParams(c, "empty ID")
+		return
+	}
+	if err := ip_service.UpdateRule(c.Request.Context(), operator(c), req); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
+
+type IPRuleDeleteReq struct {
+	ID int64 `json:"id" binding:"required"`
+}
+
+// IPRuleDelete 删除IP限制规则
+func IPRuleDelete(c *gin.Context) {
+	req := new(IPRuleDeleteReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if err := ip_service.DeleteRule(c.Request.Context(), operator(c), req.ID); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
@@ -0,0 +1,99 @@
+package admin
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/service/admin_service"
+	"wallet/common-lib/app"
+
+	"github.com/gin-gonic/gin"
+)
+
+func List(c *gin.Context) {
+	req := new(admin_service.ListAdminsReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	resp, err := admin_service.ListAdmins(c.Request.Context(), req)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.ResultPage(c, resp.List, resp.Total)
+}
+
+type DetailReq struct {
+	ID int64 `json:"id"`
+}
+
+func Detail(c *gin.Context) {
+	req := new(DetailReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if req.ID <= 0 {
+		app.InvalidParams(c, "empty ID")
+		return
+	}
+	resp, err := admin_service.AdminDetail(c.Request.Context(), req.ID)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, resp)
+}
+
+func UpdateRole(c *gin.Context) {
+	req := new(admin_service.ManageAdminReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if req.RoleID <= 0 {
+		app.InvalidParams(c, "empty role ID")
+		return
+	}
+	req.OperatorID = auth.AdminID(c)
+	if err := admin_service.UpdateAdminRole(c.Request.Context(), req); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
+
+func Enable(c *gin.Context) {
+	setStatus(c, true)
+}
+
+func Disable(c *gin.Context) {
+	setStatus(c, false)
+}
+
+func setStatus(c *gin.Context, enable bool) {
+	req := new(admin_service.ManageAdminReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	req.OperatorID = auth.AdminID(c)
+	if err := admin_service.SetAdminStatus(c.Request.Context(), req, enable); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
+
+func Delete(c *gin.Context) {
+	req := new(admin_service.ManageAdminReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	req.OperatorID = auth.AdminID(c)
+	if err := admin_service.DeleteAdmin(c.Request.Context(), req); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
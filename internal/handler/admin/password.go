@@ -0,0 +1,47 @@
+package admin
+
+import (
+	adminApp "admin/internal/app"
+	"admin/internal/app/codex"
+	"admin/internal/common/auth"
+	"admin/internal/service/admin_service"
+	"errors"
+	"wallet/common-lib/app"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChangePassword 修改自己的密码
+func ChangePassword(c *gin.Context) {
+	req := new(admin_service.ChangePasswordReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if err := admin_service.ChangePassword(c.Request.Context(), auth.AdminID(c), req); err != nil {
+		var weakErr *admin_service.WeakPasswordError
+		if errors.As(err, &weakErr) {
+			adminApp.Failed(c, codex.WeakPassword, "%s", weakErr.Msg)
+			return
+		}
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
+
+// ResetPassword 超级管理员重置其他管理员的密码
+func ResetPassword(c *gin.Context) {
+	req := new(admin_service.ResetPasswordReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	req.OperatorID = auth.AdminID(c)
+	resp, err := admin_service.ResetPassword(c.Request.Context(), req)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, resp)
+}
@@ -0,0 +1,55 @@
+package admin
+
+import (
+	"admin/internal/service/admin_service"
+	"wallet/common-lib/app"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateRole 新增角色
+func CreateRole(c *gin.Context) {
+	req := new(admin_service.RoleReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	role, err := admin_service.CreateRole(c.Request.Context(), operator(c), req)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, role)
+}
+
+// RenameRole 修改角色名称
+func RenameRole(c *gin.Context) {
+	req := new(admin_service.RoleReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if req.ID <= 0 {
+		app.InvalidParams(c, "empty ID")
+		return
+	}
+	if err := admin_service.RenameRole(c.Request.Context(), operator(c), req); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
+
+// DeleteRole 删除角色
+func DeleteRole(c *gin.Context) {
+	req := new(admin_service.DeleteRoleReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if err := admin_service.DeleteRole(c.Request.Context(), operator(c), req); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
@@ -0,0 +1,94 @@
+package admin
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/service/admin_service"
+	"admin/internal/service/session_service"
+	"wallet/common-lib/app"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sessions 当前管理员的会话列表
+func Sessions(c *gin.Context) {
+	list, err := session_service.List(c.Request.Context(), auth.AdminID(c), auth.GetSessionID(c))
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, gin.H{
+		"sessions": list,
+	})
+}
+
+type RevokeSessionReq struct {
+	ID string `json:"id" binding:"required"`
+}
+
+// RevokeSession 注销当前管理员的指定会话
+func RevokeSession(c *gin.Context) {
+	req := new(RevokeSessionReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if err := session_service.Revoke(c.Request.Context(), auth.AdminID(c), req.ID); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
+
+// RevokeOtherSessions 注销当前管理员除本会话以外的全部会话
+func RevokeOtherSessions(c *gin.Context) {
+	n, err := session_service.RevokeOthers(c.Request.Context(), auth.AdminID(c), auth.GetSessionID(c))
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, gin.H{
+		"revoked": n,
+	})
+}
+
+// Logout 退出登录，删除当前会话
+func Logout(c *gin.Context) {
+	if err := session_service.Logout(c.Request.Context(), auth.AdminID(c), auth.GetSessionID(c)); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
+
+// ForceLogout 强制下线其他管理员的全部会话
+func ForceLogout(c *gin.Context) {
+	req := new(admin_service.ForceLogoutReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	req.OperatorID = auth.AdminID(c)
+	n, err := admin_service.ForceLogout(c.Request.Context(), req)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, gin.H{
+		"revoked": n,
+	})
+}
+
+// StepUp 当前会话重新验证身份，用于执行敏感操作
+func StepUp(c *gin.Context) {
+	req := new(admin_service.StepUpReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	resp, err := admin_service.StepUp(c.Request.Context(), auth.AdminID(c), auth.GetSessionID(c), c.ClientIP(), req, svrConf)
+	if err != nil {
+		authFailed(c, err)
+		return
+	}
+	app.Result(c, resp)
+}
@@ -0,0 +1,75 @@
+package admin
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/service/admin_service"
+	"wallet/common-lib/app"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MFAStatus 当前管理员的二次验证状态
+func MFAStatus(c *gin.Context) {
+	resp, err := admin_service.GetMFAStatus(c.Request.Context(), auth.AdminID(c))
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, resp)
+}
+
+// WebauthnRegisterBegin 开始注册安全密钥
+func WebauthnRegisterBegin(c *gin.Context) {
+	req := new(admin_service.WebauthnRegisterBeginReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	resp, err := admin_service.BeginWebauthnRegister(c.Request.Context(), auth.AdminID(c), req)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, resp)
+}
+
+// WebauthnRegisterFinish 完成注册安全密钥
+func WebauthnRegisterFinish(c *gin.Context) {
+	req := new(admin_service.WebauthnRegisterFinishReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	resp, err := admin_service.FinishWebauthnRegister(c.Request.Context(), auth.AdminID(c), req)
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, resp)
+}
+
+// WebauthnCredentials 当前管理员的安全密钥列表
+func WebauthnCredentials(c *gin.Context) {
+	list, err := admin_service.ListWebauthnCredentials(c.Request.Context(), auth.AdminID(c))
+	if err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Result(c, gin.H{
+		"credentials": list,
+	})
+}
+
+// RevokeWebauthnCredential 删除当前管理员的安全密钥
+func RevokeWebauthnCredential(c *gin.Context) {
+	req := new(admin_service.RevokeWebauthnReq)
+	if err := c.ShouldBindJSON(req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+	if err := admin_service.RevokeWebauthnCredential(c.Request.Context(), auth.AdminID(c), req); err != nil {
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+	app.Success(c)
+}
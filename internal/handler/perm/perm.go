@@ -15,6 +15,11 @@ import (
 )
 
 type UpdatePermissionsReq struct {
+	Permissions []string `json:"permissions"` // 在角色权限之外额外授予的权限
+	Denies      []string `json:"denies"`      // 从角色权限中排除的权限
+}
+
+type UpdateRolePermissionsReq struct {
 	Permissions []string `json:"permissions"`
 }
 
@@ -25,22 +30,45 @@ func GetAllPermissions(c *gin.Context) {
 	})
 }
 
+// GetPermCatalog 权限目录，含每个权限码的名称、说明、模块、上级和各语言标签
+func GetPermCatalog(c *gin.Context) {
+	app.Result(c, gin.H{
+		"list": auth.PermCatalog(),
+		"tree": auth.PermTree(nil),
+	})
+}
+
+// GetCurrentUserMenus 当前管理员有权限访问的菜单树
+func GetCurrentUserMenus(c *gin.Context) {
+	tree, err := perm_service.MenuTree(c.Request.Context(), auth.AdminID(c))
+	if err != nil {
+		zapx.ErrorCtx(c.Request.Context(), "failed to get current user menus", zap.Error(err))
+		app.InternalError(c, "failed to get current user menus")
+		return
+	}
+
+	app.Result(c, gin.H{
+		"menus": tree,
+	})
+}
+
 func GetCurrentUserPermissions(c *gin.Context) {
 	currentUID := auth.AdminID(c)
 
-	perms, err := perm_service.UserPerms(c.Request.Context(), currentUID)
+	set, err := perm_service.UserPerms(c.Request.Context(), currentUID)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		zapx.ErrorCtx(c.Request.Context(), "failed to get current user permissions", zap.Error(err))
 		app.InternalError(c, "failed to get current user permissions")
 		return
 	}
-
-	if perms == nil {
-		perms = []string{}
+	if set == nil {
+		set = &auth.PermSet{}
 	}
 
 	app.Result(c, gin.H{
-		"permissions": perms,
+		"permissions": set.Expand(),
+		"grants":      set.Grants,
+		"denies":      set.Denies,
 	})
 }
 
@@ -52,20 +80,17 @@ func GetUserPermissions(c *gin.Context) {
 		return
 	}
 
-	perms, err := perm_service.UserPerms(c.Request.Context(), uid)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+	detail, err := perm_service.UserPermDetail(c.Request.Context(), uid)
+	if err != nil {
 		zapx.ErrorCtx(c.Request.Context(), "failed to get user permissions", zap.Error(err))
 		app.InternalError(c, "failed to get user permissions")
 		return
 	}
 
-	if perms == nil {
-		perms = []string{}
-	}
-
 	app.Result(c, gin.H{
 		"uid":         uid,
-		"permissions": perms,
+		"permissions": detail.Effective,
+		"detail":      detail,
 	})
 }
 
@@ -83,7 +108,7 @@ func UpdateUserPermissions(c *gin.Context) {
 		return
 	}
 
-	if err := perm_service.UpdateUserPermissions(c.Request.Context(), auth.AdminID(c), uid, req.Permissions); err != nil {
+	if err := perm_service.UpdateUserPermissions(c.Request.Context(), auth.AdminID(c), uid, req.Permissions, req.Denies); err != nil {
 		zapx.ErrorCtx(c.Request.Context(), "failed to update user permissions", zap.Error(err))
 		app.InternalError(c, "%s", err.Error())
 		return
@@ -91,3 +116,45 @@ func UpdateUserPermissions(c *gin.Context) {
 
 	app.Success(c)
 }
+
+func GetRolePermissions(c *gin.Context) {
+	rid, err := strconv.Atoi(c.Param("rid"))
+	if err != nil {
+		app.InvalidParams(c, "invalid role id format")
+		return
+	}
+
+	perms, err := perm_service.RolePerms(c.Request.Context(), rid)
+	if err != nil {
+		zapx.ErrorCtx(c.Request.Context(), "failed to get role permissions", zap.Error(err))
+		app.InternalError(c, "failed to get role permissions")
+		return
+	}
+
+	app.Result(c, gin.H{
+		"role_id":     rid,
+		"permissions": perms,
+	})
+}
+
+func UpdateRolePermissions(c *gin.Context) {
+	rid, err := strconv.Atoi(c.Param("rid"))
+	if err != nil {
+		app.InvalidParams(c, "invalid role id format")
+		return
+	}
+
+	var req UpdateRolePermissionsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		app.InvalidParams(c, "%s", err.Error())
+		return
+	}
+
+	if err := perm_service.UpdateRolePermissions(c.Request.Context(), auth.AdminID(c), rid, req.Permissions); err != nil {
+		zapx.ErrorCtx(c.Request.Context(), "failed to update role permissions", zap.Error(err))
+		app.InternalError(c, "%s", err.Error())
+		return
+	}
+
+	app.Success(c)
+}
@@ -1,11 +1,17 @@
 package middleware
 
 import (
+	adminApp "admin/internal/app"
+	"admin/internal/app/codex"
 	"admin/internal/common/auth"
-	"encoding/json"
+	"admin/internal/model"
+	"admin/internal/service/session_service"
+	"admin/internal/service/token_service"
 	"errors"
+	"fmt"
+	"reflect"
+	"time"
 	"wallet/common-lib/app"
-	"wallet/common-lib/rdb"
 	"wallet/common-lib/zapx"
 
 	"github.com/gin-gonic/gin"
@@ -13,39 +19,120 @@ import (
 	"go.uber.org/zap"
 )
 
+// authPC Auth返回的处理函数的代码地址；使用具名函数而不是闭包，
+// 闭包在Auth被内联到其他包时会生成新的函数，地址不同
+var authPC = reflect.ValueOf(authenticate).Pointer()
+
+// IsAuth h是否为Auth中间件，用于注册路由时判断路由是否需要登录
+func IsAuth(h gin.HandlerFunc) bool {
+	return reflect.ValueOf(h).Pointer() == authPC
+}
+
 func Auth() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		sid := auth.GetSessionID(c)
-		if sid == "" {
-			app.Unauthorized(c, "empty session")
+	return authenticate
+}
+
+// authenticate 校验会话或API令牌
+func authenticate(c *gin.Context) {
+	sid := auth.GetSessionID(c)
+	if sid == "" {
+		if token := auth.GetAPIToken(c); token != "" {
+			tokenAuth(c, token)
 			return
 		}
-		var (
-			rds = rdb.Client
-			ctx = c.Request.Context()
-		)
-		data, err := rds.Get(ctx, sid).Bytes()
-		if err != nil {
-			if errors.Is(err, redis.Nil) {
-				app.Unauthorized(c, "session expired")
-			} else {
-				app.Unauthorized(c, "auth error")
-			}
-			zapx.ErrorCtx(ctx, "read session cache error", zap.Error(err))
-			return
+		app.Unauthorized(c, "empty session")
+		return
+	}
+	ctx := c.Request.Context()
+	user, err := auth.GetSession(ctx, sid)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			app.Unauthorized(c, "session expired")
+		} else {
+			app.Unauthorized(c, "auth error")
 		}
-		user := new(auth.User)
-		if err = json.Unmarshal(data, user); err != nil {
-			zapx.ErrorCtx(ctx, "unmarshal session cache error", zap.Error(err))
-			app.Unauthorized(c, "invalid session data")
-			return
+		zapx.ErrorCtx(ctx, "read session cache error", zap.Error(err))
+		return
+	}
+	// 先核对角色，会话超时配置按角色生效
+	if err = session_service.Validate(ctx, sid, user); err != nil {
+		if errors.Is(err, session_service.ErrAdminDisabled) || errors.Is(err, session_service.ErrPasswordChanged) {
+			app.Unauthorized(c, err.Error())
+		} else {
+			app.Unauthorized(c, "auth error")
 		}
-		_ = rds.Expire(ctx, sid, auth.SessionExpireTime)
+		return
+	}
+	now := time.Now()
+	limits := session_service.GetLimits(ctx, user.Role)
+	if err = session_service.CheckTimeout(limits, user, now); err != nil {
+		_ = auth.DelSession(ctx, user.ID, sid)
+		code := codex.SessionIdleTimeout
+		if errors.Is(err, session_service.ErrSessionExpired) {
+			code = codex.SessionExpired
+		}
+		adminApp.UnauthorizedCode(c, code, err.Error())
+		return
+	}
+	if err = session_service.CheckBinding(ctx, user, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		adminApp.UnauthorizedCode(c, codex.SessionHijacked, err.Error())
+		return
+	}
+	_ = auth.TouchSession(ctx, sid, user, session_service.TTL(limits, user, now))
+
+	// 受限会话只能访问作用域允许的接口
+	key := fmt.Sprintf("%s:%s", c.Request.Method, c.FullPath())
+	if !auth.ScopeAllows(user.Scope, key) {
+		adminApp.UnauthorizedCode(c, adminApp.ScopeCodes[user.Scope], "请先完成账号安全设置")
+		return
+	}
+	// 敏感操作需要近期重新验证过身份
+	if auth.NeedsStepUp(user, key, now) {
+		adminApp.UnauthorizedCode(c, codex.StepUpRequired, "请先验证身份")
+		return
+	}
+
+	c.Set(auth.ReqAdminID, user.ID)
+	c.Set(auth.ReqRoleID, user.Role)
+	c.Set(auth.ReqAdminAccount, user.Account)
 
-		c.Set(auth.ReqAdminID, user.ID)
-		c.Set(auth.ReqRoleID, user.Role)
-		c.Set(auth.ReqAdminAccount, user.Account)
+	c.Next()
+}
 
-		c.Next()
+// tokenAuth 服务账号通过API令牌访问，只能访问有权限码的接口，且不能访问需要重新验证身份的敏感接口
+func tokenAuth(c *gin.Context, token string) {
+	key := fmt.Sprintf("%s:%s", c.Request.Method, c.FullPath())
+	if _, ok := auth.AllRouterPerms[key]; !ok || auth.StepUpRouters[key] {
+		adminApp.PermDenied(c)
+		return
 	}
+	ctx := c.Request.Context()
+	var (
+		p   *token_service.Principal
+		err error
+	)
+	// Signature已读取过令牌记录的，不再重复查询
+	v, _ := c.Get(auth.ReqAPIToken)
+	t, ok := v.(*model.AdminAPIToken)
+	if !ok {
+		t, err = token_service.Load(ctx, token)
+	}
+	if err == nil {
+		p, err = token_service.Authenticate(ctx, t, c.ClientIP())
+	}
+	if err != nil {
+		if errors.Is(err, token_service.ErrInvalidToken) {
+			app.Unauthorized(c, err.Error())
+		} else {
+			app.Unauthorized(c, "auth error")
+		}
+		return
+	}
+
+	c.Set(auth.ReqAdminID, p.Admin.ID)
+	c.Set(auth.ReqRoleID, p.Admin.RoleID)
+	c.Set(auth.ReqAdminAccount, p.Admin.Account)
+	c.Set(auth.ReqTokenScopes, p.Scopes)
+
+	c.Next()
 }
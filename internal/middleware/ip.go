@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	adminApp "admin/internal/app"
+	"admin/internal/app/codex"
+	"admin/internal/common/auth"
+	"admin/internal/service/ip_service"
+	"wallet/common-lib/app"
+	"wallet/common-lib/zapx"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// IPGuard 按全局、角色、管理员三级IP限制校验每个请求，需放在Auth之后
+func IPGuard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		adminID, ip := auth.AdminID(c), c.ClientIP()
+		allowed, err := ip_service.Allowed(ctx, adminID, auth.AdminRole(c), ip)
+		if err != nil {
+			app.InternalError(c, "ip check error")
+			c.Abort()
+			return
+		}
+		if !allowed {
+			zapx.WarnCtx(ctx, "request ip not allowed",
+				zap.Int64("admin_id", adminID),
+				zap.String("ip", ip),
+				zap.String("path", c.FullPath()))
+			adminApp.UnauthorizedCode(c, codex.IPNotAllowed, "当前网络不允许访问")
+			return
+		}
+		c.Next()
+	}
+}
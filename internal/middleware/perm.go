@@ -27,5 +27,5 @@ func check(c *gin.Context, perm auth.PermCode) bool {
 	if userID <= 0 {
 		return false
 	}
-	return perm_service.CheckPerms(c, userID, perm)
+	return auth.TokenAllows(c, perm) && perm_service.CheckPerms(c, userID, perm)
 }
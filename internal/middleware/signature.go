@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	adminApp "admin/internal/app"
+	"admin/internal/app/codex"
+	"admin/internal/common/auth"
+	"admin/internal/service/token_service"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"wallet/common-lib/app"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	SignatureHeader = "X-Signature"
+	TimestampHeader = "X-Timestamp"
+	NonceHeader     = "X-Nonce"
+
+	maxSignedBodyBytes = 8 << 20
+)
+
+// Signature 校验API令牌请求的HMAC签名，防止请求被篡改和重放；会话请求不受影响，需放在Auth之前
+func Signature() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := auth.GetAPIToken(c)
+		if token == "" || auth.GetSessionID(c) != "" {
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSignedBodyBytes))
+			if err != nil {
+				app.InvalidParams(c, "read body error")
+				c.Abort()
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		path := c.Request.URL.Path
+		if c.Request.URL.RawQuery != "" {
+			path += "?" + c.Request.URL.RawQuery
+		}
+		ctx := c.Request.Context()
+		t, err := token_service.Load(ctx, token)
+		if err == nil {
+			c.Set(auth.ReqAPIToken, t)
+			err = token_service.VerifySignature(ctx, t, &token_service.SignedRequest{
+				Method:    c.Request.Method,
+				Path:      path,
+				Timestamp: c.GetHeader(TimestampHeader),
+				Nonce:     c.GetHeader(NonceHeader),
+				Signature: c.GetHeader(SignatureHeader),
+				Body:      body,
+			})
+		}
+		switch {
+		case err == nil:
+			c.Next()
+		case errors.Is(err, token_service.ErrInvalidToken):
+			app.Unauthorized(c, err.Error())
+		case errors.Is(err, token_service.ErrSignatureRequired),
+			errors.Is(err, token_service.ErrSignatureInvalid),
+			errors.Is(err, token_service.ErrTimestampStale),
+			errors.Is(err, token_service.ErrNonceReused):
+			adminApp.UnauthorizedCode(c, codex.SignatureInvalid, err.Error())
+		default:
+			app.Unauthorized(c, "auth error")
+		}
+	}
+}
@@ -5,19 +5,25 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Admin struct {
-	ID          int64      `gorm:"column:id" json:"id"`
-	Account     string     `gorm:"column:account" json:"account"`
-	Password    string     `gorm:"column:password" json:"-"`
-	RoleID      int        `gorm:"column:role_id" json:"role_id"`
-	Status      int        `gorm:"column:status" json:"status"`
-	LastLoginAt *time.Time `gorm:"column:last_login_at" json:"last_login_at"`
-	LastLoginIP string     `gorm:"column:last_login_ip" json:"last_login_ip"`
-	MfaSecret   []byte     `gorm:"column:mfa_secret" json:"-"`
-	CreatedAt   time.Time  `gorm:"column:created_at" json:"created_at"`
-	UpdatedAt   time.Time  `gorm:"column:updated_at" json:"updated_at"`
+	ID            int64      `gorm:"column:id" json:"id"`
+	Account       string     `gorm:"column:account" json:"account"`
+	Password      string     `gorm:"column:password" json:"-"`
+	RoleID        int        `gorm:"column:role_id" json:"role_id"`
+	Status        int        `gorm:"column:status" json:"status"`
+	IsService     int        `gorm:"column:is_service" json:"is_service"`
+	LastLoginAt   *time.Time `gorm:"column:last_login_at" json:"last_login_at"`
+	LastLoginIP   string     `gorm:"column:last_login_ip" json:"last_login_ip"`
+	MfaSecret     []byte     `gorm:"column:mfa_secret" json:"-"`
+	MfaReenroll   int        `gorm:"column:mfa_reenroll" json:"mfa_reenroll"`
+	MustChangePwd int        `gorm:"column:must_change_pwd" json:"must_change_pwd"`
+	PwdChangedAt  *time.Time `gorm:"column:pwd_changed_at" json:"pwd_changed_at"`
+	DeletedAt     *time.Time `gorm:"column:deleted_at" json:"-"`
+	CreatedAt     time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"column:updated_at" json:"updated_at"`
 }
 
 func (*Admin) TableName() string {
@@ -29,14 +35,62 @@ func (u *Admin) Create(ctx context.Context, db *gorm.DB) error {
 	return db.WithContext(ctx).Create(u).Error
 }
 
-// GetByID 根据ID获取用户
+// GetByID 根据ID获取用户（不含已删除）
 func (u *Admin) GetByID(ctx context.Context, db *gorm.DB, userID int64) error {
-	return db.WithContext(ctx).Where("`id` = ?", userID).Take(u).Error
+	return db.WithContext(ctx).Where("`id` = ? AND `deleted_at` IS NULL", userID).Take(u).Error
 }
 
-// GetByAccount 根据账号获取用户
+// GetByAccount 根据账号获取用户（不含已删除）
 func (u *Admin) GetByAccount(ctx context.Context, db *gorm.DB, account string) error {
-	return db.WithContext(ctx).Where("`account` = ?", account).Take(u).Error
+	return db.WithContext(ctx).Where("`account` = ? AND `deleted_at` IS NULL", account).Take(u).Error
+}
+
+// AdminFilter 管理员列表筛选条件，nil表示不筛选
+type AdminFilter struct {
+	Account       string
+	RoleID        int
+	Status        *int
+	MfaBound      *bool
+	LastLoginFrom *time.Time
+	LastLoginTo   *time.Time
+}
+
+// GetList 分页查询管理员列表（不含已删除）
+func (u *Admin) GetList(ctx context.Context, db *gorm.DB, page, pageSize int, f *AdminFilter) ([]*Admin, int64, error) {
+	var list []*Admin
+	var total int64
+
+	offset := (page - 1) * pageSize
+	query := db.WithContext(ctx).Table(u.TableName()).Where("`deleted_at` IS NULL")
+	if f.Account != "" {
+		query = query.Where("`account` LIKE ?", "%"+f.Account+"%")
+	}
+	if f.RoleID > 0 {
+		query = query.Where("`role_id` = ?", f.RoleID)
+	}
+	if f.Status != nil {
+		query = query.Where("`status` = ?", *f.Status)
+	}
+	if f.MfaBound != nil {
+		if *f.MfaBound {
+			query = query.Where("`mfa_secret` IS NOT NULL AND LENGTH(`mfa_secret`) > 0")
+		} else {
+			query = query.Where("(`mfa_secret` IS NULL OR LENGTH(`mfa_secret`) = 0)")
+		}
+	}
+	if f.LastLoginFrom != nil {
+		query = query.Where("`last_login_at` >= ?", *f.LastLoginFrom)
+	}
+	if f.LastLoginTo != nil {
+		query = query.Where("`last_login_at` < ?", *f.LastLoginTo)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("`id` DESC").Offset(offset).Limit(pageSize).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
 }
 
 // UpdateLoginInfo 更新登录信息
@@ -49,10 +103,11 @@ func (u *Admin) UpdateLoginInfo(ctx context.Context, db *gorm.DB, userID int64,
 	return db.WithContext(ctx).Table(u.TableName()).Where("`id` = ?", userID).Updates(dst).Error
 }
 
-// UpdateMfaSecret 更新MFA密钥
+// UpdateMfaSecret 更新MFA密钥，同时清除重新绑定标记
 func (u *Admin) UpdateMfaSecret(ctx context.Context, db *gorm.DB, userID int64, secret []byte) error {
 	dst := map[string]any{
-		"mfa_secret": secret,
+		"mfa_secret":   secret,
+		"mfa_reenroll": 0,
 	}
 	return db.WithContext(ctx).Table(u.TableName()).Where("`id` = ?", userID).Updates(dst).Error
 }
@@ -65,12 +120,118 @@ func (u *Admin) ClearMfaSecret(ctx context.Context, db *gorm.DB, userID int64) e
 	return db.WithContext(ctx).Table(u.TableName()).Where("`id` = ?", userID).Updates(dst).Error
 }
 
+// ResetMfa 清空MFA密钥并标记下次登录必须重新绑定
+func (u *Admin) ResetMfa(ctx context.Context, db *gorm.DB, userID int64) error {
+	dst := map[string]any{
+		"mfa_secret":   nil,
+		"mfa_reenroll": 1,
+	}
+	return db.WithContext(ctx).Table(u.TableName()).Where("`id` = ?", userID).Updates(dst).Error
+}
+
+// UpdatePassword 更新密码并记录修改时间，mustChange表示下次登录必须修改密码
+func (u *Admin) UpdatePassword(ctx context.Context, db *gorm.DB, userID int64, password string, mustChange bool) error {
+	flag := 0
+	if mustChange {
+		flag = 1
+	}
+	now := time.Now()
+	dst := map[string]any{
+		"password":        password,
+		"must_change_pwd": flag,
+		"pwd_changed_at":  &now,
+	}
+	return db.WithContext(ctx).Table(u.TableName()).Where("`id` = ?", userID).Updates(dst).Error
+}
+
+// StartPwdClock 尚未记录密码修改时间的管理员从现在开始计算密码有效期
+func (u *Admin) StartPwdClock(ctx context.Context, db *gorm.DB, userID int64) error {
+	return db.WithContext(ctx).Table(u.TableName()).
+		Where("`id` = ? AND `pwd_changed_at` IS NULL", userID).
+		Update("pwd_changed_at", time.Now()).Error
+}
+
 // Update 更新用户信息
 func (u *Admin) Update(ctx context.Context, db *gorm.DB, userID int64, dst map[string]any) error {
 	return db.WithContext(ctx).Table(u.TableName()).Where("`id` = ?", userID).Updates(dst).Error
 }
 
-// AccountExists 检查账号是否存在
+// SoftDelete 软删除用户，同时禁用账号
+func (u *Admin) SoftDelete(ctx context.Context, db *gorm.DB, userID int64) error {
+	now := time.Now()
+	dst := map[string]any{
+		"status":     0,
+		"deleted_at": &now,
+	}
+	return db.WithContext(ctx).Table(u.TableName()).Where("`id` = ? AND `deleted_at` IS NULL", userID).Updates(dst).Error
+}
+
+// GetIDsByRole 获取角色下全部管理员的ID（不含已删除）
+func (u *Admin) GetIDsByRole(ctx context.Context, db *gorm.DB, roleID int) ([]int64, error) {
+	var ids []int64
+	err := db.WithContext(ctx).Table(u.TableName()).
+		Where("`role_id` = ? AND `deleted_at` IS NULL", roleID).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// CountByRole 统计每个角色的管理员数量（不含已删除）
+func (u *Admin) CountByRole(ctx context.Context, db *gorm.DB) (map[int]int64, error) {
+	var rows []struct {
+		RoleID int
+		Count  int64
+	}
+	err := db.WithContext(ctx).Table(u.TableName()).
+		Select("`role_id`, COUNT(*) AS `count`").
+		Where("`deleted_at` IS NULL").
+		Group("`role_id`").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[int]int64, len(rows))
+	for _, r := range rows {
+		m[r.RoleID] = r.Count
+	}
+	return m, nil
+}
+
+// LockIDsByRole 在事务中锁定角色下的全部管理员记录（含已删除），返回未删除的管理员ID
+func (u *Admin) LockIDsByRole(ctx context.Context, tx *gorm.DB, roleID int) ([]int64, error) {
+	var rows []struct {
+		ID        int64
+		DeletedAt *time.Time
+	}
+	err := tx.WithContext(ctx).Table(u.TableName()).
+		Select("`id`, `deleted_at`").
+		Where("`role_id` = ?", roleID).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(rows))
+	for _, r := range rows {
+		if r.DeletedAt == nil {
+			ids = append(ids, r.ID)
+		}
+	}
+	return ids, nil
+}
+
+// DetachDeletedRole 已删除的管理员不再关联该角色，角色删除时使用
+func (u *Admin) DetachDeletedRole(ctx context.Context, db *gorm.DB, roleID int) error {
+	return db.WithContext(ctx).Table(u.TableName()).
+		Where("`role_id` = ? AND `deleted_at` IS NOT NULL", roleID).
+		Update("role_id", 0).Error
+}
+
+// ReassignRole 把角色下的全部管理员转移到另一个角色（含已删除，避免留下指向不存在角色的记录）
+func (u *Admin) ReassignRole(ctx context.Context, db *gorm.DB, fromRoleID, toRoleID int) error {
+	return db.WithContext(ctx).Table(u.TableName()).Where("`role_id` = ?", fromRoleID).Update("role_id", toRoleID).Error
+}
+
+// AccountExists 检查账号是否存在（含已删除，账号不可复用）
 func (u *Admin) AccountExists(ctx context.Context, db *gorm.DB, account string) (bool, error) {
 	var count int64
 	err := db.WithContext(ctx).Table(u.TableName()).Where("`account` = ?", account).Count(&count).Error
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type AdminAPIToken struct {
+	ID            int64          `gorm:"column:id" json:"id"`
+	AdminID       int64          `gorm:"column:admin_id" json:"admin_id"`
+	Name          string         `gorm:"column:name" json:"name"`
+	Prefix        string         `gorm:"column:prefix" json:"prefix"`
+	TokenHash     string         `gorm:"column:token_hash" json:"-"`
+	Scopes        datatypes.JSON `gorm:"column:scopes;type:json" json:"scopes"`
+	Signed        int            `gorm:"column:signed" json:"signed"`
+	SigningSecret []byte         `gorm:"column:signing_secret" json:"-"`
+	ExpiresAt     time.Time      `gorm:"column:expires_at" json:"expires_at"`
+	LastUsedAt    *time.Time     `gorm:"column:last_used_at" json:"last_used_at"`
+	LastUsedIP    string         `gorm:"column:last_used_ip" json:"last_used_ip"`
+	RevokedAt     *time.Time     `gorm:"column:revoked_at" json:"revoked_at"`
+	CreatedBy     int64          `gorm:"column:created_by" json:"created_by"`
+	CreatedAt     time.Time      `gorm:"column:created_at" json:"created_at"`
+}
+
+func (*AdminAPIToken) TableName() string {
+	return "admin_api_tokens"
+}
+
+// Create 创建令牌
+func (t *AdminAPIToken) Create(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Create(t).Error
+}
+
+// GetByHash 根据令牌哈希获取令牌（含已撤销、已过期）
+func (t *AdminAPIToken) GetByHash(ctx context.Context, db *gorm.DB, hash string) error {
+	return db.WithContext(ctx).Where("`token_hash` = ?", hash).Take(t).Error
+}
+
+// GetByID 根据ID获取令牌
+func (t *AdminAPIToken) GetByID(ctx context.Context, db *gorm.DB, id int64) error {
+	return db.WithContext(ctx).Where("`id` = ?", id).Take(t).Error
+}
+
+// GetList 获取令牌列表，adminID>0时只返回该账号的
+func (t *AdminAPIToken) GetList(ctx context.Context, db *gorm.DB, adminID int64) ([]*AdminAPIToken, error) {
+	var list []*AdminAPIToken
+	query := db.WithContext(ctx).Table(t.TableName())
+	if adminID > 0 {
+		query = query.Where("`admin_id` = ?", adminID)
+	}
+	err := query.Order("`id` DESC").Find(&list).Error
+	return list, err
+}
+
+// UpdateUsage 记录最近使用时间和IP
+func (t *AdminAPIToken) UpdateUsage(ctx context.Context, db *gorm.DB, id int64, ip string) error {
+	dst := map[string]any{
+		"last_used_at": time.Now(),
+		"last_used_ip": ip,
+	}
+	return db.WithContext(ctx).Table(t.TableName()).Where("`id` = ?", id).Updates(dst).Error
+}
+
+// Revoke 撤销令牌，返回是否撤销成功（已撤销的返回false）
+func (t *AdminAPIToken) Revoke(ctx context.Context, db *gorm.DB, id int64) (bool, error) {
+	res := db.WithContext(ctx).Table(t.TableName()).
+		Where("`id` = ? AND `revoked_at` IS NULL", id).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected > 0, res.Error
+}
+
+// RevokeByAdmin 撤销账号的全部令牌
+func (t *AdminAPIToken) RevokeByAdmin(ctx context.Context, db *gorm.DB, adminID int64) error {
+	return db.WithContext(ctx).Table(t.TableName()).
+		Where("`admin_id` = ? AND `revoked_at` IS NULL", adminID).
+		Update("revoked_at", time.Now()).Error
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type AdminAuditLog struct {
+	ID              int64          `gorm:"column:id" json:"id"`
+	OperatorID      int64          `gorm:"column:operator_id" json:"operator_id"`
+	OperatorAccount string         `gorm:"column:operator_account" json:"operator_account"`
+	IP              string         `gorm:"column:ip" json:"ip"`
+	Action          string         `gorm:"column:action" json:"action"`
+	Target          string         `gorm:"column:target" json:"target"`
+	Detail          datatypes.JSON `gorm:"column:detail;type:json" json:"detail"`
+	CreatedAt       time.Time      `gorm:"column:created_at" json:"created_at"`
+}
+
+func (*AdminAuditLog) TableName() string {
+	return "admin_audit_logs"
+}
+
+// Create 写入审计日志
+func (l *AdminAuditLog) Create(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Create(l).Error
+}
+
+// AuditLogFilter 审计日志筛选条件
+type AuditLogFilter struct {
+	OperatorID int64
+	Action     string
+	Target     string
+	From       *time.Time
+	To         *time.Time
+}
+
+// GetList 分页查询审计日志
+func (l *AdminAuditLog) GetList(ctx context.Context, db *gorm.DB, page, pageSize int, f *AuditLogFilter) ([]*AdminAuditLog, int64, error) {
+	var list []*AdminAuditLog
+	var total int64
+
+	offset := (page - 1) * pageSize
+	query := db.WithContext(ctx).Table(l.TableName())
+	if f.OperatorID > 0 {
+		query = query.Where("`operator_id` = ?", f.OperatorID)
+	}
+	if f.Action != "" {
+		query = query.Where("`action` LIKE ?", f.Action+"%")
+	}
+	if f.Target != "" {
+		query = query.Where("`target` = ?", f.Target)
+	}
+	if f.From != nil {
+		query = query.Where("`created_at` >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("`created_at` < ?", *f.To)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("`id` DESC").Offset(offset).Limit(pageSize).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
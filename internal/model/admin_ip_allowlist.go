@@ -0,0 +1,49 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdminIPAllowlist struct {
+	ID        int64      `gorm:"column:id" json:"id"`
+	CIDR      string     `gorm:"column:cidr" json:"cidr"`
+	Label     string     `gorm:"column:label" json:"label"`
+	ExpiresAt *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	CreatedBy int64      `gorm:"column:created_by" json:"created_by"`
+	CreatedAt time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (*AdminIPAllowlist) TableName() string {
+	return "admin_ip_allowlist"
+}
+
+// GetAll 获取全部白名单（含已过期）
+func (a *AdminIPAllowlist) GetAll(ctx context.Context, db *gorm.DB) ([]*AdminIPAllowlist, error) {
+	var list []*AdminIPAllowlist
+	err := db.WithContext(ctx).Table(a.TableName()).Order("`id` ASC").Find(&list).Error
+	return list, err
+}
+
+// GetByID 根据ID获取白名单
+func (a *AdminIPAllowlist) GetByID(ctx context.Context, db *gorm.DB, id int64) error {
+	return db.WithContext(ctx).Where("`id` = ?", id).Take(a).Error
+}
+
+// Create 创建白名单
+func (a *AdminIPAllowlist) Create(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Create(a).Error
+}
+
+// Update 更新白名单
+func (a *AdminIPAllowlist) Update(ctx context.Context, db *gorm.DB, id int64, dst map[string]any) error {
+	return db.WithContext(ctx).Table(a.TableName()).Where("`id` = ?", id).Updates(dst).Error
+}
+
+// Delete 删除白名单
+func (a *AdminIPAllowlist) Delete(ctx context.Context, db *gorm.DB, id int64) error {
+	return db.WithContext(ctx).Where("`id` = ?", id).Delete(a).Error
+}
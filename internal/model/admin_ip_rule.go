@@ -0,0 +1,62 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IP限制规则的作用级别，全局级别即登录IP白名单
+const (
+	IPRuleLevelRole  = "role"
+	IPRuleLevelAdmin = "admin"
+)
+
+type AdminIPRule struct {
+	ID        int64      `gorm:"column:id" json:"id"`
+	Level     string     `gorm:"column:level" json:"level"`
+	TargetID  int64      `gorm:"column:target_id" json:"target_id"`
+	CIDR      string     `gorm:"column:cidr" json:"cidr"`
+	Label     string     `gorm:"column:label" json:"label"`
+	ExpiresAt *time.Time `gorm:"column:expires_at" json:"expires_at"`
+	CreatedBy int64      `gorm:"column:created_by" json:"created_by"`
+	CreatedAt time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (*AdminIPRule) TableName() string {
+	return "admin_ip_rules"
+}
+
+// GetAll 获取全部规则（含已过期）
+func (r *AdminIPRule) GetAll(ctx context.Context, db *gorm.DB) ([]*AdminIPRule, error) {
+	var list []*AdminIPRule
+	err := db.WithContext(ctx).Table(r.TableName()).Order("`id` ASC").Find(&list).Error
+	return list, err
+}
+
+// GetByID 根据ID获取规则
+func (r *AdminIPRule) GetByID(ctx context.Context, db *gorm.DB, id int64) error {
+	return db.WithContext(ctx).Where("`id` = ?", id).Take(r).Error
+}
+
+// Create 创建规则
+func (r *AdminIPRule) Create(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Create(r).Error
+}
+
+// Update 更新规则
+func (r *AdminIPRule) Update(ctx context.Context, db *gorm.DB, id int64, dst map[string]any) error {
+	return db.WithContext(ctx).Table(r.TableName()).Where("`id` = ?", id).Updates(dst).Error
+}
+
+// Delete 删除规则
+func (r *AdminIPRule) Delete(ctx context.Context, db *gorm.DB, id int64) error {
+	return db.WithContext(ctx).Where("`id` = ?", id).Delete(r).Error
+}
+
+// DeleteByTarget 删除某个角色或管理员的全部规则
+func (r *AdminIPRule) DeleteByTarget(ctx context.Context, db *gorm.DB, level string, targetID int64) error {
+	return db.WithContext(ctx).Where("`level` = ? AND `target_id` = ?", level, targetID).Delete(r).Error
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdminLoginLog struct {
+	ID        int64     `gorm:"column:id" json:"id"`
+	AdminID   int64     `gorm:"column:admin_id" json:"admin_id"`
+	Account   string    `gorm:"column:account" json:"account"`
+	Success   int8      `gorm:"column:success" json:"success"`
+	Reason    string    `gorm:"column:reason" json:"reason"`
+	IP        string    `gorm:"column:ip" json:"ip"`
+	UserAgent string    `gorm:"column:user_agent" json:"user_agent"`
+	MFA       string    `gorm:"column:mfa" json:"mfa"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (*AdminLoginLog) TableName() string {
+	return "admin_login_logs"
+}
+
+// Create 写入登录记录
+func (l *AdminLoginLog) Create(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Create(l).Error
+}
+
+// HasSucceeded 管理员是否登录成功过，ip非空时只统计该IP
+func (l *AdminLoginLog) HasSucceeded(ctx context.Context, db *gorm.DB, adminID int64, ip string) (bool, error) {
+	var count int64
+	query := db.WithContext(ctx).Table(l.TableName()).Where("`admin_id` = ? AND `success` = 1", adminID)
+	if ip != "" {
+		query = query.Where("`ip` = ?", ip)
+	}
+	err := query.Limit(1).Count(&count).Error
+	return count > 0, err
+}
+
+// CountFailures 统计账号自since以来、最近一次登录成功之后的连续失败次数，beforeID>0时只统计该记录之前的
+func (l *AdminLoginLog) CountFailures(ctx context.Context, db *gorm.DB, account string, since time.Time, beforeID int64) (int64, error) {
+	var lastSuccess int64
+	query := db.WithContext(ctx).Table(l.TableName()).Where("`account` = ? AND `success` = 1", account)
+	if beforeID > 0 {
+		query = query.Where("`id` < ?", beforeID)
+	}
+	if err := query.Select("COALESCE(MAX(`id`), 0)").Scan(&lastSuccess).Error; err != nil {
+		return 0, err
+	}
+
+	var count int64
+	query = db.WithContext(ctx).Table(l.TableName()).
+		Where("`account` = ? AND `success` = 0 AND `id` > ? AND `created_at` >= ?", account, lastSuccess, since)
+	if beforeID > 0 {
+		query = query.Where("`id` < ?", beforeID)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
+
+// LoginLogFilter 登录记录筛选条件
+type LoginLogFilter struct {
+	AdminID int64
+	Account string
+	Success *int8
+	IP      string
+	From    *time.Time
+	To      *time.Time
+}
+
+// GetList 分页查询登录记录
+func (l *AdminLoginLog) GetList(ctx context.Context, db *gorm.DB, page, pageSize int, f *LoginLogFilter) ([]*AdminLoginLog, int64, error) {
+	var list []*AdminLoginLog
+	var total int64
+
+	offset := (page - 1) * pageSize
+	query := db.WithContext(ctx).Table(l.TableName())
+	if f.AdminID > 0 {
+		query = query.Where("`admin_id` = ?", f.AdminID)
+	}
+	if f.Account != "" {
+		query = query.Where("`account` = ?", f.Account)
+	}
+	if f.Success != nil {
+		query = query.Where("`success` = ?", *f.Success)
+	}
+	if f.IP != "" {
+		query = query.Where("`ip` = ?", f.IP)
+	}
+	if f.From != nil {
+		query = query.Where("`created_at` >= ?", *f.From)
+	}
+	if f.To != nil {
+		query = query.Where("`created_at` < ?", *f.To)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("`id` DESC").Offset(offset).Limit(pageSize).Find(&list).Error; err != nil {
+		return nil, 0, err
+	}
+	return list, total, nil
+}
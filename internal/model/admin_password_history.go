@@ -0,0 +1,41 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdminPasswordHistory struct {
+	ID        int64     `gorm:"column:id" json:"id"`
+	AdminID   int64     `gorm:"column:admin_id" json:"admin_id"`
+	Password  string    `gorm:"column:password" json:"-"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+}
+
+func (*AdminPasswordHistory) TableName() string {
+	return "admin_password_history"
+}
+
+// Create 记录一条历史密码
+func (h *AdminPasswordHistory) Create(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Create(h).Error
+}
+
+// GetRecent 获取管理员最近使用过的n个密码
+func (h *AdminPasswordHistory) GetRecent(ctx context.Context, db *gorm.DB, adminID int64, n int) ([]*AdminPasswordHistory, error) {
+	var list []*AdminPasswordHistory
+	err := db.WithContext(ctx).Table(h.TableName()).Where("`admin_id` = ?", adminID).Order("`id` DESC").Limit(n).Find(&list).Error
+	return list, err
+}
+
+// Prune 只保留管理员最近的n条历史密码
+func (h *AdminPasswordHistory) Prune(ctx context.Context, db *gorm.DB, adminID int64, n int) error {
+	var ids []int64
+	err := db.WithContext(ctx).Table(h.TableName()).Where("`admin_id` = ?", adminID).Order("`id` DESC").Offset(n).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	return db.WithContext(ctx).Where("`id` IN ?", ids).Delete(h).Error
+}
@@ -11,7 +11,8 @@ import (
 type AdminPerm struct {
 	ID        int64          `gorm:"column:id;primaryKey" json:"id"`
 	UID       int64          `gorm:"column:uid;uniqueIndex" json:"uid"`
-	Perms     datatypes.JSON `gorm:"column:perms;type:json" json:"perms"`
+	Perms     datatypes.JSON `gorm:"column:perms;type:json" json:"perms"`   // 在角色权限之外额外授予的权限
+	Denies    datatypes.JSON `gorm:"column:denies;type:json" json:"denies"` // 从角色权限中排除的权限
 	CreatedAt int64          `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt int64          `gorm:"column:updated_at" json:"updated_at"`
 }
@@ -24,12 +25,13 @@ func (up *AdminPerm) GetByUID(ctx context.Context, db *gorm.DB, uid int64) error
 	return db.WithContext(ctx).Where("uid = ?", uid).Take(up).Error
 }
 
-func (up *AdminPerm) CreateOrUpdate(ctx context.Context, db *gorm.DB, uid int64, perms datatypes.JSON) error {
+func (up *AdminPerm) CreateOrUpdate(ctx context.Context, db *gorm.DB, uid int64, perms, denies datatypes.JSON) error {
 	now := time.Now().Unix()
 	result := db.WithContext(ctx).Model(&AdminPerm{}).
 		Where("uid = ?", uid).
 		Updates(map[string]interface{}{
 			"perms":      perms,
+			"denies":     denies,
 			"updated_at": now,
 		})
 
@@ -37,6 +39,7 @@ func (up *AdminPerm) CreateOrUpdate(ctx context.Context, db *gorm.DB, uid int64,
 		return db.WithContext(ctx).Create(&AdminPerm{
 			UID:       uid,
 			Perms:     perms,
+			Denies:    denies,
 			CreatedAt: now,
 			UpdatedAt: now,
 		}).Error
@@ -0,0 +1,59 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AdminRecoveryCode struct {
+	ID        int64      `gorm:"column:id" json:"id"`
+	AdminID   int64      `gorm:"column:admin_id" json:"admin_id"`
+	CodeHash  string     `gorm:"column:code_hash" json:"-"`
+	UsedAt    *time.Time `gorm:"column:used_at" json:"used_at"`
+	UsedIP    string     `gorm:"column:used_ip" json:"used_ip"`
+	CreatedAt time.Time  `gorm:"column:created_at" json:"created_at"`
+}
+
+func (*AdminRecoveryCode) TableName() string {
+	return "admin_recovery_codes"
+}
+
+// Replace 删除管理员原有的恢复码并写入新的恢复码
+func (r *AdminRecoveryCode) Replace(ctx context.Context, db *gorm.DB, adminID int64, list []*AdminRecoveryCode) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("`admin_id` = ?", adminID).Delete(r).Error; err != nil {
+			return err
+		}
+		return tx.Create(&list).Error
+	})
+}
+
+// DeleteByAdmin 删除管理员的全部恢复码
+func (r *AdminRecoveryCode) DeleteByAdmin(ctx context.Context, db *gorm.DB, adminID int64) error {
+	return db.WithContext(ctx).Where("`admin_id` = ?", adminID).Delete(r).Error
+}
+
+// GetUnused 根据哈希获取管理员未使用的恢复码
+func (r *AdminRecoveryCode) GetUnused(ctx context.Context, db *gorm.DB, adminID int64, codeHash string) error {
+	return db.WithContext(ctx).Where("`admin_id` = ? AND `code_hash` = ? AND `used_at` IS NULL", adminID, codeHash).Take(r).Error
+}
+
+// MarkUsed 标记恢复码已使用，返回是否标记成功（并发使用时只有一次成功）
+func (r *AdminRecoveryCode) MarkUsed(ctx context.Context, db *gorm.DB, id int64, ip string) (bool, error) {
+	now := time.Now()
+	dst := map[string]any{
+		"used_at": &now,
+		"used_ip": ip,
+	}
+	res := db.WithContext(ctx).Table(r.TableName()).Where("`id` = ? AND `used_at` IS NULL", id).Updates(dst)
+	return res.RowsAffected > 0, res.Error
+}
+
+// CountUnused 统计管理员剩余可用的恢复码数量
+func (r *AdminRecoveryCode) CountUnused(ctx context.Context, db *gorm.DB, adminID int64) (int64, error) {
+	var count int64
+	err := db.WithContext(ctx).Table(r.TableName()).Where("`admin_id` = ? AND `used_at` IS NULL", adminID).Count(&count).Error
+	return count, err
+}
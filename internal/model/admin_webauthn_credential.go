@@ -0,0 +1,58 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type AdminWebauthnCredential struct {
+	ID           int64          `gorm:"column:id" json:"id"`
+	AdminID      int64          `gorm:"column:admin_id" json:"admin_id"`
+	Name         string         `gorm:"column:name" json:"name"`
+	CredentialID []byte         `gorm:"column:credential_id" json:"-"`
+	Credential   datatypes.JSON `gorm:"column:credential;type:json" json:"-"` // webauthn.Credential，含公钥和签名计数
+	LastUsedAt   *time.Time     `gorm:"column:last_used_at" json:"last_used_at"`
+	LastUsedIP   string         `gorm:"column:last_used_ip" json:"last_used_ip"`
+	CreatedAt    time.Time      `gorm:"column:created_at" json:"created_at"`
+}
+
+func (*AdminWebauthnCredential) TableName() string {
+	return "admin_webauthn_credentials"
+}
+
+// Create 保存凭证
+func (w *AdminWebauthnCredential) Create(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Create(w).Error
+}
+
+// GetByAdmin 获取管理员的全部凭证
+func (w *AdminWebauthnCredential) GetByAdmin(ctx context.Context, db *gorm.DB, adminID int64) ([]*AdminWebauthnCredential, error) {
+	var list []*AdminWebauthnCredential
+	err := db.WithContext(ctx).Table(w.TableName()).Where("`admin_id` = ?", adminID).Order("`id` ASC").Find(&list).Error
+	return list, err
+}
+
+// UpdateUsage 登录后更新凭证（签名计数）和使用记录
+func (w *AdminWebauthnCredential) UpdateUsage(ctx context.Context, db *gorm.DB, id int64, credential datatypes.JSON, ip string) error {
+	now := time.Now()
+	dst := map[string]any{
+		"credential":   credential,
+		"last_used_at": &now,
+		"last_used_ip": ip,
+	}
+	return db.WithContext(ctx).Table(w.TableName()).Where("`id` = ?", id).Updates(dst).Error
+}
+
+// Delete 删除管理员的指定凭证，返回是否删除成功
+func (w *AdminWebauthnCredential) Delete(ctx context.Context, db *gorm.DB, adminID, id int64) (bool, error) {
+	res := db.WithContext(ctx).Where("`id` = ? AND `admin_id` = ?", id, adminID).Delete(w)
+	return res.RowsAffected > 0, res.Error
+}
+
+// DeleteByAdmin 删除管理员的全部凭证
+func (w *AdminWebauthnCredential) DeleteByAdmin(ctx context.Context, db *gorm.DB, adminID int64) error {
+	return db.WithContext(ctx).Where("`admin_id` = ?", adminID).Delete(w).Error
+}
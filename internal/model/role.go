@@ -40,3 +40,18 @@ func (r *Role) Exists(ctx context.Context, db *gorm.DB, roleID int) (bool, error
 	err := db.WithContext(ctx).Table(r.TableName()).Where("`id` = ?", roleID).Count(&count).Error
 	return count > 0, err
 }
+
+// Create 创建角色
+func (r *Role) Create(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Create(r).Error
+}
+
+// Rename 修改角色名称
+func (r *Role) Rename(ctx context.Context, db *gorm.DB, roleID int, name string) error {
+	return db.WithContext(ctx).Table(r.TableName()).Where("`id` = ?", roleID).Update("name", name).Error
+}
+
+// Delete 删除角色
+func (r *Role) Delete(ctx context.Context, db *gorm.DB, roleID int) error {
+	return db.WithContext(ctx).Where("`id` = ?", roleID).Delete(r).Error
+}
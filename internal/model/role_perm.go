@@ -0,0 +1,51 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// RolePerm 角色的权限模板，该角色的管理员默认拥有这些权限
+type RolePerm struct {
+	ID        int64          `gorm:"column:id;primaryKey" json:"id"`
+	RoleID    int            `gorm:"column:role_id;uniqueIndex" json:"role_id"`
+	Perms     datatypes.JSON `gorm:"column:perms;type:json" json:"perms"`
+	CreatedAt int64          `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt int64          `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (*RolePerm) TableName() string {
+	return "role_perms"
+}
+
+func (rp *RolePerm) GetByRoleID(ctx context.Context, db *gorm.DB, roleID int) error {
+	return db.WithContext(ctx).Where("role_id = ?", roleID).Take(rp).Error
+}
+
+func (rp *RolePerm) CreateOrUpdate(ctx context.Context, db *gorm.DB, roleID int, perms datatypes.JSON) error {
+	now := time.Now().Unix()
+	result := db.WithContext(ctx).Model(&RolePerm{}).
+		Where("role_id = ?", roleID).
+		Updates(map[string]interface{}{
+			"perms":      perms,
+			"updated_at": now,
+		})
+
+	if result.RowsAffected == 0 {
+		return db.WithContext(ctx).Create(&RolePerm{
+			RoleID:    roleID,
+			Perms:     perms,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}).Error
+	}
+
+	return result.Error
+}
+
+func (rp *RolePerm) DeleteByRoleID(ctx context.Context, db *gorm.DB, roleID int) error {
+	return db.WithContext(ctx).Where("role_id = ?", roleID).Delete(rp).Error
+}
@@ -9,21 +9,26 @@ import (
 	"admin/internal/handler/perm"
 	"admin/internal/middleware"
 	"net/http"
+	"wallet/common-lib/config"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Init(engine *gin.Engine) {
-	engine.GET("ping", func(c *gin.Context) {
+func Init(engine *gin.Engine, svrConf *config.ServiceConfig) {
+	adminHandler.Init(svrConf)
+
+	routerx.Get(&engine.RouterGroup, "ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"message": "pong",
 		})
-	})
+	}, routerx.Public())
 	r := engine.Group("/api/v1")
 	adminRouter(r)
 	memberRouter(r.Group("/member"))
 	permRouter(r.Group("/perm"))
 	agentRouter(r.Group("/agent"))
+
+	routerx.Report(engine)
 }
 
 // adminRouter 管理员相关路由
@@ -31,36 +36,89 @@ func adminRouter(r *gin.RouterGroup) {
 	a := r.Group("/admin")
 
 	// 不需要认证的接口
-	routerx.Post(a, "/login", adminHandler.Login)
+	routerx.Post(a, "/login", adminHandler.Login, routerx.Public())
 
 	// 需要认证的接口
 	authGroup := a.Group("")
-	authGroup.Use(middleware.Auth())
+	authGroup.Use(middleware.Auth(), middleware.IPGuard())
 	{
-		routerx.Get(authGroup, "/roles", adminHandler.GetRoles)
-		routerx.Post(authGroup, "/create", adminHandler.CreateAdmin)
-		routerx.Post(authGroup, "/mfa/generate", adminHandler.GenerateMFASecret)
-		routerx.Post(authGroup, "/mfa/bind", adminHandler.BindMFA)
-		routerx.Post(authGroup, "/mfa/unbind", adminHandler.UnbindMFA)
+		routerx.Get(authGroup, "/roles", adminHandler.GetRoles, routerx.Public())
+		routerx.PostPerm(authGroup, "/roles/create", auth.RoleCreate, adminHandler.CreateRole)
+		routerx.PostPerm(authGroup, "/roles/rename", auth.RoleRename, adminHandler.RenameRole)
+		routerx.PostPerm(authGroup, "/roles/delete", auth.RoleDelete, adminHandler.DeleteRole, routerx.StepUp())
+		routerx.PostPerm(authGroup, "/create", auth.AdminCreate, adminHandler.CreateAdmin)
+		routerx.Post(authGroup, "/mfa/generate", adminHandler.GenerateMFASecret, routerx.AllowScope(auth.ScopeMFAEnroll), routerx.Public())
+		routerx.Post(authGroup, "/mfa/bind", adminHandler.BindMFA, routerx.AllowScope(auth.ScopeMFAEnroll), routerx.Public())
+		routerx.PostPerm(authGroup, "/mfa/unbind", auth.AdminUnbindMFA, adminHandler.UnbindMFA)
+		routerx.PostPerm(authGroup, "/mfa/reset", auth.AdminResetMFA, adminHandler.ResetMFA)
+		routerx.Get(authGroup, "/mfa/status", adminHandler.MFAStatus, routerx.AllowScope(auth.ScopeMFAEnroll), routerx.Public())
+		routerx.Post(authGroup, "/webauthn/register/begin", adminHandler.WebauthnRegisterBegin, routerx.AllowScope(auth.ScopeMFAEnroll), routerx.Public())
+		routerx.Post(authGroup, "/webauthn/register/finish", adminHandler.WebauthnRegisterFinish, routerx.AllowScope(auth.ScopeMFAEnroll), routerx.Public())
+		routerx.Get(authGroup, "/webauthn/credentials", adminHandler.WebauthnCredentials, routerx.Public())
+		routerx.Post(authGroup, "/webauthn/credentials/revoke", adminHandler.RevokeWebauthnCredential, routerx.Public())
+		routerx.Post(authGroup, "/mfa/recovery-codes/regenerate", adminHandler.RegenerateRecoveryCodes, routerx.Public())
+		routerx.Get(authGroup, "/sessions", adminHandler.Sessions, routerx.Public())
+		routerx.Post(authGroup, "/sessions/revoke", adminHandler.RevokeSession, routerx.Public())
+		routerx.Post(authGroup, "/sessions/revoke-others", adminHandler.RevokeOtherSessions, routerx.Public())
+		routerx.Post(authGroup, "/logout", adminHandler.Logout, routerx.AllowScope(auth.ScopeChangePassword, auth.ScopePasswordExpired, auth.ScopeMFAEnroll), routerx.Public())
+		routerx.Post(authGroup, "/stepup", adminHandler.StepUp, routerx.Public())
+		routerx.Post(authGroup, "/password/change", adminHandler.ChangePassword, routerx.AllowScope(auth.ScopeChangePassword, auth.ScopePasswordExpired), routerx.Public())
+		routerx.PostPerm(authGroup, "/password/reset", auth.AdminResetPassword, adminHandler.ResetPassword)
+		routerx.PostPerm(authGroup, "/list", auth.AdminList, adminHandler.List)
+		routerx.PostPerm(authGroup, "/detail", auth.AdminDetail, adminHandler.Detail)
+		routerx.PostPerm(authGroup, "/role/update", auth.AdminEditRole, adminHandler.UpdateRole)
+		routerx.PostPerm(authGroup, "/enable", auth.AdminEnable, adminHandler.Enable)
+		routerx.PostPerm(authGroup, "/disable", auth.AdminDisable, adminHandler.Disable)
+		routerx.PostPerm(authGroup, "/delete", auth.AdminDelete, adminHandler.Delete)
+		routerx.PostPerm(authGroup, "/force-logout", auth.AdminForceLogout, adminHandler.ForceLogout)
+		routerx.PostPerm(authGroup, "/unlock-login", auth.AdminUnlockLogin, adminHandler.UnlockLogin)
+		routerx.PostPerm(authGroup, "/login-logs", auth.AdminLoginLogs, adminHandler.LoginLogs)
+		routerx.PostPerm(authGroup, "/api-tokens/list", auth.APITokenList, adminHandler.APITokenList)
+		routerx.PostPerm(authGroup, "/api-tokens/create", auth.APITokenCreate, adminHandler.APITokenCreate, routerx.StepUp())
+		routerx.PostPerm(authGroup, "/api-tokens/revoke", auth.APITokenRevoke, adminHandler.APITokenRevoke)
+		routerx.PostPerm(authGroup, "/allowlist/list", auth.AllowlistList, adminHandler.AllowlistList)
+		routerx.PostPerm(authGroup, "/allowlist/create", auth.AllowlistCreate, adminHandler.AllowlistCreate)
+		routerx.PostPerm(authGroup, "/allowlist/update", auth.AllowlistUpdate, adminHandler.AllowlistUpdate)
+		routerx.PostPerm(authGroup, "/allowlist/delete", auth.AllowlistDelete, adminHandler.AllowlistDelete)
+		routerx.PostPerm(authGroup, "/ip-rules/list", auth.IPRuleList, adminHandler.IPRuleList)
+		routerx.PostPerm(authGroup, "/ip-rules/create", auth.IPRuleCreate, adminHandler.IPRuleCreate)
+		routerx.PostPerm(authGroup, "/ip-rules/update", auth.IPRuleUpdate, adminHandler.IPRuleUpdate)
+		routerx.PostPerm(authGroup, "/ip-rules/delete", auth.IPRuleDelete, adminHandler.IPRuleDelete)
+		routerx.PostPerm(authGroup, "/audit/list", auth.AuditLogList, adminHandler.AuditLogs)
 	}
 }
 
 func permRouter(r *gin.RouterGroup) {
-	r.GET("/", perm.GetAllPermissions)
-	r.GET("/current", perm.GetCurrentUserPermissions)
-	r.GET("/:uid", perm.GetUserPermissions)
-	r.POST("/:uid", perm.UpdateUserPermissions)
+	ag := r.Group("", middleware.Auth(), middleware.IPGuard())
+	{
+		routerx.Get(ag, "/", perm.GetAllPermissions, routerx.Public())
+		routerx.Get(ag, "/current", perm.GetCurrentUserPermissions, routerx.Public())
+		routerx.Get(ag, "/catalog", perm.GetPermCatalog, routerx.Public())
+		routerx.Get(ag, "/menus", perm.GetCurrentUserMenus, routerx.Public())
+		routerx.GetPerm(ag, "/:uid", auth.AdminPermView, perm.GetUserPermissions)
+		routerx.PostPerm(ag, "/:uid", auth.AdminPermEdit, perm.UpdateUserPermissions)
+	}
+
+	// 角色权限模板
+	rg := r.Group("/role", middleware.Auth(), middleware.IPGuard())
+	{
+		routerx.GetPerm(rg, "/:rid", auth.RolePermView, perm.GetRolePermissions)
+		routerx.PostPerm(rg, "/:rid", auth.RolePermEdit, perm.UpdateRolePermissions)
+	}
 }
 
 func memberRouter(r *gin.RouterGroup) {
-	routerx.PostPerm(r, "/list", auth.MemberList, member.List)
+	mg := r.Group("", middleware.Auth(), middleware.IPGuard())
+	{
+		routerx.PostPerm(mg, "/list", auth.MemberList, member.List)
+	}
 }
 
 func agentRouter(r *gin.RouterGroup) {
-	re := r.Group("/review", middleware.Auth())
+	re := r.Group("/review", middleware.Auth(), middleware.IPGuard())
 	{
-		re.POST("/list", review.List)
-		re.POST("/approve", review.Approve)
-		re.POST("/reject", review.Reject)
+		routerx.PostPerm(re, "/list", auth.AgentReviewList, review.List)
+		routerx.PostPerm(re, "/approve", auth.AgentReviewApprove, review.Approve, routerx.StepUp())
+		routerx.PostPerm(re, "/reject", auth.AgentReviewReject, review.Reject, routerx.StepUp())
 	}
 }
@@ -1,14 +1,15 @@
 package admin_service
 
 import (
+	"admin/internal/app/codex"
 	"admin/internal/common/auth"
 	"admin/internal/model"
+	"admin/internal/service/ip_service"
+	"admin/internal/service/session_service"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"regexp"
-	"strings"
 	"time"
 	"wallet/common-lib/config"
 	"wallet/common-lib/consts/rds_keys"
@@ -27,7 +28,13 @@ import (
 
 // GetRolesResp 获取角色列表响应
 type GetRolesResp struct {
-	Roles []*model.Role `json:"roles"`
+	Roles []*RoleItem `json:"roles"`
+}
+
+// RoleItem 角色信息
+type RoleItem struct {
+	*model.Role
+	AdminCount int64 `json:"admin_count"` // 该角色的管理员数量
 }
 
 // GetRoles 获取角色列表
@@ -38,14 +45,25 @@ func GetRoles(ctx context.Context) (*GetRolesResp, error) {
 		zapx.ErrorCtx(ctx, "get roles failed", zap.Error(err))
 		return nil, err
 	}
-	return &GetRolesResp{Roles: roles}, nil
+	counts, err := new(model.Admin).CountByRole(ctx, dbs.Admin)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "count admins by role failed", zap.Error(err))
+		return nil, err
+	}
+	items := make([]*RoleItem, 0, len(roles))
+	for _, r := range roles {
+		items = append(items, &RoleItem{Role: r, AdminCount: counts[r.ID]})
+	}
+	return &GetRolesResp{Roles: items}, nil
 }
 
 // CreateAdminReq 创建管理员请求
 type CreateAdminReq struct {
-	Account  string `json:"account" binding:"required"`
-	Password string `json:"password" binding:"required"`
-	RoleID   int    `json:"role_id" binding:"required"`
+	OperatorID int64  `json:"-"`
+	Account    string `json:"account" binding:"required"`
+	Password   string `json:"password" binding:"required_unless=IsService true"`
+	RoleID     int    `json:"role_id" binding:"required"`
+	IsService  bool   `json:"is_service"` // 服务账号，不能登录，只能通过API令牌访问
 }
 
 // CreateAdmin 创建管理员
@@ -60,6 +78,17 @@ func CreateAdmin(ctx context.Context, req *CreateAdminReq) error {
 	if !exists {
 		return errors.New("角色不存在")
 	}
+	operatorModel := new(model.Admin)
+	if err = operatorModel.GetByID(ctx, dbs.Admin, req.OperatorID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("操作者不存在")
+		}
+		zapx.ErrorCtx(ctx, "get operator by id failed", zap.Error(err))
+		return err
+	}
+	if err = checkRoleGrant(ctx, operatorModel.ID, operatorModel.RoleID, req.RoleID); err != nil {
+		return err
+	}
 
 	// 检查账号是否已存在
 	userModel := new(model.Admin)
@@ -72,7 +101,19 @@ func CreateAdmin(ctx context.Context, req *CreateAdminReq) error {
 		return errors.New("账号已存在")
 	}
 
-	// todo 之后看具体需求对密码明文长度或做强密码校验
+	isService := 0
+	if req.IsService {
+		// 服务账号使用随机密码，不能登录
+		isService = 1
+		if req.Password, err = oneTimePassword(); err != nil {
+			zapx.ErrorCtx(ctx, "generate service account password failed", zap.Error(err))
+			return err
+		}
+	} else if err = ValidatePassword(ctx, req.Account, req.Password); err != nil {
+		// 校验密码强度
+		return err
+	}
+
 	// 加密密码
 	hashedPassword, err := bcryptx.Hash(req.Password)
 	if err != nil {
@@ -81,32 +122,46 @@ func CreateAdmin(ctx context.Context, req *CreateAdminReq) error {
 	}
 
 	// 创建用户
+	now := time.Now()
 	user := &model.Admin{
-		Account:  req.Account,
-		Password: hashedPassword,
-		RoleID:   req.RoleID,
-		Status:   1, // 默认启用
+		Account:      req.Account,
+		Password:     hashedPassword,
+		RoleID:       req.RoleID,
+		Status:       1, // 默认启用
+		IsService:    isService,
+		PwdChangedAt: &now,
 	}
 
 	if err = user.Create(ctx, dbs.Admin); err != nil {
 		zapx.ErrorCtx(ctx, "create admin user failed", zap.Error(err))
 		return err
 	}
+	// 初始密码计入历史密码，修改密码时不能再改回
+	if isService == 0 {
+		recordPasswordHistory(ctx, user.ID, hashedPassword)
+	}
 
 	return nil
 }
 
 // LoginReq 登录请求
 type LoginReq struct {
-	Account  string `json:"account" binding:"required"`
-	Password string `json:"password" binding:"required"`
-	TotpCode string `json:"totp_code"` // 谷歌验证器动态码
+	Account      string `json:"account" binding:"required"`
+	Password     string `json:"password" binding:"required"`
+	TotpCode     string `json:"totp_code"`     // 谷歌验证器动态码
+	RecoveryCode string `json:"recovery_code"` // 恢复码，丢失验证器时代替动态码使用
+	EnrollToken  string `json:"enroll_token"`  // 重新绑定令牌，谷歌验证器被重置后代替动态码使用
+
+	WebAuthn json.RawMessage `json:"webauthn"` // 安全密钥断言，navigator.credentials.get的返回值
 }
 
 // LoginResp 登录响应
 type LoginResp struct {
-	SessionID string `json:"session_id"`
-	User      *User  `json:"user"`
+	SessionID string     `json:"session_id"`
+	User      *User      `json:"user"`
+	Scope     auth.Scope `json:"scope,omitempty"` // 非空时为受限会话，只能访问对应的接口
+
+	RecoveryCodesLeft *int64 `json:"recovery_codes_left,omitempty"` // 使用恢复码登录时返回剩余数量
 }
 
 type User struct {
@@ -115,31 +170,50 @@ type User struct {
 	RoleID       int    `json:"role_id"`
 	IsSuperAdmin bool   `json:"is_super_admin"`
 	MfaEnabled   bool   `json:"mfa_enabled"`
+	MfaEnrollBy  int64  `json:"mfa_enroll_by,omitempty"` // 角色要求二次验证时的绑定截止时间戳
+}
+
+// Login 管理员登录，每次登录结果都写入登录记录
+func Login(ctx context.Context, req *LoginReq, loginIP, userAgent string, svrConf *config.ServiceConfig) (*LoginResp, error) {
+	ev := &loginEvent{
+		Account:   req.Account,
+		IP:        loginIP,
+		UserAgent: userAgent,
+	}
+	resp, err := login(ctx, req, ev, svrConf)
+	recordLogin(ctx, ev, err)
+	return resp, err
 }
 
-// Login 管理员登录
-func Login(ctx context.Context, req *LoginReq, loginIP string, svrConf *config.ServiceConfig) (*LoginResp, error) {
+func login(ctx context.Context, req *LoginReq, ev *loginEvent, svrConf *config.ServiceConfig) (*LoginResp, error) {
+	loginIP, userAgent := ev.IP, ev.UserAgent
+	// 检查登录锁定
+	if err := checkLoginGuard(ctx, req.Account, loginIP); err != nil {
+		return nil, err
+	}
+
 	// 查询用户
 	userModel := new(model.Admin)
 	if err := userModel.GetByAccount(ctx, dbs.Admin, req.Account); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("账号或密码错误")
+			return nil, loginFailed(ctx, req.Account, loginIP, "账号或密码错误")
 		}
 		zapx.ErrorCtx(ctx, "get user by account failed", zap.Error(err))
 		return nil, err
 	}
+	ev.AdminID = userModel.ID
 
 	// 检查用户状态
 	if userModel.Status == 0 {
 		return nil, errors.New("账号已被禁用")
 	}
 
-	// 验证密码
-	if !bcryptx.Check(userModel.Password, req.Password) {
-		return nil, errors.New("账号或密码错误")
+	// 验证密码，服务账号不能登录
+	if userModel.IsService == 1 || !bcryptx.Check(userModel.Password, req.Password) {
+		return nil, loginFailed(ctx, req.Account, loginIP, "账号或密码错误")
 	}
-	// 检查IP白名单
-	ipAllowed, err := ValidateLoginIP(ctx, loginIP)
+	// 检查IP白名单及角色、管理员的IP限制
+	ipAllowed, err := ip_service.Allowed(ctx, userModel.ID, userModel.RoleID, loginIP)
 	if err != nil {
 		zapx.ErrorCtx(ctx, "validate login ip failed", zap.Error(err))
 		return nil, errors.New("登录验证失败")
@@ -149,56 +223,69 @@ func Login(ctx context.Context, req *LoginReq, loginIP string, svrConf *config.S
 		return nil, errors.New("登录IP不在白名单内")
 	}
 
-	// 如果启用了谷歌验证器，验证动态码
-	if len(userModel.MfaSecret) > 0 && svrConf.Service.Auth.Login.Totp {
-		if req.TotpCode == "" {
-			return nil, errors.New("请输入谷歌验证器动态码")
+	// 启用二次验证时，校验谷歌验证器动态码、安全密钥或恢复码
+	var recoveryLeft *int64
+	if svrConf.Service.Auth.Login.Totp {
+		if ev.MFA, recoveryLeft, err = verifyMFA(ctx, userModel, req, loginIP); err != nil {
+			return nil, err
 		}
+	}
+	mfaEnrolled := ev.MFA != ""
 
-		// 解密MFA密钥
-		secret, err := kms_rpcx.Decrypt(ctx, userModel.MfaSecret, kms.PurposeUserTotpSecret, userModel.ID)
+	// 谷歌验证器被超级管理员重置的，用重新绑定令牌代替动态码
+	reenroll := userModel.MfaReenroll == 1 && svrConf.Service.Auth.Login.Totp
+	if reenroll {
+		if req.EnrollToken == "" {
+			return nil, errors.New("谷歌验证器已被重置，请输入重新绑定令牌")
+		}
+		ok, err := checkEnrollToken(ctx, userModel.ID, req.EnrollToken)
 		if err != nil {
-			zapx.ErrorCtx(ctx, "decrypt err", zap.Error(err))
-			return nil, fmt.Errorf("decrypt err: %v", err)
+			return nil, err
 		}
-		// 验证TOTP码
-		if !authx.ValidateTOTP(secret, req.TotpCode) {
-			return nil, errors.New("谷歌验证器动态码错误")
+		if !ok {
+			return nil, loginFailed(ctx, req.Account, loginIP, "重新绑定令牌错误或已过期")
 		}
 	}
 
-	// 生成session
-	sessionID, err := auth.NewSessionID()
-	if err != nil {
-		zapx.ErrorCtx(ctx, "generate session id failed", zap.Error(err))
-		return nil, err
+	// 需要修改密码的账号只发放受限会话
+	var scope auth.Scope
+	if userModel.MustChangePwd == 1 {
+		scope = auth.ScopeChangePassword
+	} else {
+		switch passwordExpiry(ctx, userModel, time.Now()) {
+		case pwdExpiredGrace:
+			scope = auth.ScopePasswordExpired
+		case pwdExpired:
+			return nil, &LoginError{Code: codex.PasswordExpired, Msg: "密码已过期，请联系超级管理员重置"}
+		}
 	}
 
-	// 保存session到Redis
-	sessionUser := &auth.User{
-		ID:      userModel.ID,
-		Account: userModel.Account,
-		Role:    userModel.RoleID,
-		LoginIP: loginIP,
-		LoginAt: time.Now().Unix(),
+	// 需要重新绑定，或角色要求绑定谷歌验证器但超过宽限期仍未绑定的，只能绑定验证器
+	var mfaEnrollBy time.Time
+	if svrConf.Service.Auth.Login.Totp {
+		mfaEnrollBy = mfaEnrollDeadline(ctx, userModel, mfaEnrolled)
+		if scope == "" && (reenroll || !mfaEnrollBy.IsZero() && !time.Now().Before(mfaEnrollBy)) {
+			scope = auth.ScopeMFAEnroll
+		}
 	}
 
-	sessionData, err := json.Marshal(sessionUser)
+	// 生成session
+	sessionID, err := session_service.Create(ctx, userModel, loginIP, userAgent, scope)
 	if err != nil {
-		zapx.ErrorCtx(ctx, "marshal session data failed", zap.Error(err))
-		return nil, err
-	}
-
-	if err = rdb.Client.Set(ctx, sessionID, sessionData, auth.SessionExpireTime).Err(); err != nil {
-		zapx.ErrorCtx(ctx, "save session to redis failed", zap.Error(err))
 		return nil, err
 	}
+	loginSucceeded(ctx, req.Account)
 
 	// 更新登录信息
 	if err = userModel.UpdateLoginInfo(ctx, dbs.Admin, userModel.ID, loginIP); err != nil {
 		zapx.ErrorCtx(ctx, "update login info failed", zap.Error(err))
 		// 不影响登录流程，只记录日志
 	}
+	if userModel.PwdChangedAt == nil {
+		if err = userModel.StartPwdClock(ctx, dbs.Admin, userModel.ID); err != nil {
+			zapx.ErrorCtx(ctx, "start password clock failed", zap.Int64("user_id", userModel.ID), zap.Error(err))
+		}
+	}
 
 	return &LoginResp{
 		SessionID: sessionID,
@@ -207,14 +294,91 @@ func Login(ctx context.Context, req *LoginReq, loginIP string, svrConf *config.S
 			Account:      userModel.Account,
 			RoleID:       userModel.RoleID,
 			IsSuperAdmin: userModel.RoleID == auth.SuperAdminRoleID,
-			MfaEnabled:   len(userModel.MfaSecret) > 0,
+			MfaEnabled:   mfaEnrolled || len(userModel.MfaSecret) > 0,
+			MfaEnrollBy:  unixOrZero(mfaEnrollBy),
 		},
+		Scope:             scope,
+		RecoveryCodesLeft: recoveryLeft,
 	}, nil
 }
 
-// GenerateMFASecretReq 生成MFA密钥请求
-type GenerateMFASecretReq struct {
-	UserID int64 `json:"user_id"`
+// verifyMFA 校验二次验证，返回通过校验的验证方式，未绑定任何二次验证方式时为空；未提供验证时返回可用的验证方式
+func verifyMFA(ctx context.Context, userModel *model.Admin, req *LoginReq, loginIP string) (string, *int64, error) {
+	user, err := loadWebauthnUser(ctx, userModel)
+	if err != nil {
+		return "", nil, err
+	}
+	totpBound := len(userModel.MfaSecret) > 0
+	webauthnBound := len(user.creds) > 0
+	if !totpBound && !webauthnBound {
+		return "", nil, nil
+	}
+
+	switch {
+	case req.TotpCode != "" && totpBound:
+		ok, err := checkTOTP(ctx, userModel, req.TotpCode)
+		if err != nil {
+			return "", nil, err
+		}
+		if !ok {
+			return "", nil, loginFailed(ctx, req.Account, loginIP, "谷歌验证器动态码错误")
+		}
+		return mfaFactorTotp, nil, nil
+	case len(req.WebAuthn) > 0 && webauthnBound:
+		ok, err := finishWebauthnLogin(ctx, user, req.WebAuthn, loginIP)
+		if err != nil {
+			return "", nil, err
+		}
+		if !ok {
+			return "", nil, loginFailed(ctx, req.Account, loginIP, "安全密钥验证失败")
+		}
+		return mfaFactorWebauthn, nil, nil
+	case req.RecoveryCode != "":
+		ok, left, err := useRecoveryCode(ctx, userModel, req.RecoveryCode, loginIP)
+		if err != nil {
+			return "", nil, err
+		}
+		if !ok {
+			return "", nil, loginFailed(ctx, req.Account, loginIP, "恢复码错误或已使用")
+		}
+		return mfaFactorRecovery, &left, nil
+	default:
+		e := new(MFARequiredError)
+		if totpBound {
+			e.Factors = append(e.Factors, mfaFactorTotp)
+		}
+		if webauthnBound {
+			assertion, err := beginWebauthnLogin(ctx, user)
+			if err != nil && !errors.Is(err, ErrWebauthnDisabled) {
+				return "", nil, err
+			}
+			if assertion != nil {
+				e.Factors = append(e.Factors, mfaFactorWebauthn)
+				e.WebAuthn = assertion
+			}
+		}
+		e.Factors = append(e.Factors, mfaFactorRecovery)
+		return "", nil, e
+	}
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// checkTOTP 校验管理员的谷歌验证器动态码
+func checkTOTP(ctx context.Context, userModel *model.Admin, code string) (bool, error) {
+	// 解密MFA密钥
+	secret, err := kms_rpcx.Decrypt(ctx, userModel.MfaSecret, kms.PurposeUserTotpSecret, userModel.ID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "decrypt err", zap.Error(err))
+		return false, fmt.Errorf("decrypt err: %v", err)
+	}
+	// 验证TOTP码
+	return useTOTP(ctx, userModel.ID, secret, code)
 }
 
 // GenerateMFASecretResp 生成MFA密钥响应
@@ -222,7 +386,7 @@ type GenerateMFASecretResp struct {
 	QRCode string `json:"qr_code"` // base64编码的二维码图片
 }
 
-// GenerateMFASecret 生成谷歌验证器密钥并返回二维码
+// GenerateMFASecret 为当前管理员生成谷歌验证器密钥并返回二维码
 func GenerateMFASecret(ctx context.Context, userID int64, issuer string) (*GenerateMFASecretResp, error) {
 	if rdb.Client == nil {
 		return nil, errors.New("MFA功能需要Redis支持，当前Redis不可用")
@@ -264,61 +428,71 @@ func GenerateMFASecret(ctx context.Context, userID int64, issuer string) (*Gener
 
 // BindMFAReq 绑定MFA请求
 type BindMFAReq struct {
-	UserID   int64  `json:"user_id"`
 	Password string `json:"password" binding:"required"`
 	TotpCode string `json:"totp_code" binding:"required"`
 }
 
-// BindMFA 绑定谷歌验证器
-func BindMFA(ctx context.Context, req *BindMFAReq) error {
+// BindMFA 为当前管理员绑定谷歌验证器，成功后返回一组恢复码
+func BindMFA(ctx context.Context, userID int64, req *BindMFAReq) (*RecoveryCodesResp, error) {
 	// 查询用户
 	userModel := new(model.Admin)
-	if err := userModel.GetByID(ctx, dbs.Admin, req.UserID); err != nil {
+	if err := userModel.GetByID(ctx, dbs.Admin, userID); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return errors.New("用户不存在")
+			return nil, errors.New("用户不存在")
 		}
 		zapx.ErrorCtx(ctx, "get user by id failed", zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	// 验证密码
 	if !bcryptx.Check(userModel.Password, req.Password) {
-		return errors.New("密码错误")
+		return nil, errors.New("密码错误")
 	}
 
 	// 从Redis获取临时密钥
-	redisKey := rds_keys.AdminMFATempSecret(req.UserID)
+	redisKey := rds_keys.AdminMFATempSecret(userID)
 	secret, err := rdb.Client.Get(ctx, redisKey).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
-			return errors.New("密钥已过期，请重新获取二维码")
+			return nil, errors.New("密钥已过期，请重新获取二维码")
 		}
 		zapx.ErrorCtx(ctx, "get temp mfa secret from redis failed", zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	// 验证TOTP码
-	if !authx.ValidateTOTP(secret, req.TotpCode) {
-		return errors.New("谷歌验证器动态码错误")
+	ok, err := useTOTP(ctx, userModel.ID, secret, req.TotpCode)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("谷歌验证器动态码错误")
+	}
+
+	// 生成恢复码，未绑定时恢复码不会被使用，因此先于密钥保存
+	codes, err := resetRecoveryCodes(ctx, userModel.ID)
+	if err != nil {
+		return nil, err
 	}
 
 	// 加密密钥
 	blob, _, err := kms_rpcx.Encrypt(ctx, secret, kms.PurposeUserTotpSecret, userModel.ID)
 	if err != nil {
 		zapx.ErrorCtx(ctx, "encrypt error", zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	// 保存到数据库
-	if err = userModel.UpdateMfaSecret(ctx, dbs.Admin, req.UserID, blob); err != nil {
+	if err = userModel.UpdateMfaSecret(ctx, dbs.Admin, userID, blob); err != nil {
 		zapx.ErrorCtx(ctx, "update mfa secret failed", zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	// 删除临时密钥
 	_ = rdb.Client.Del(ctx, redisKey).Err()
+	afterMFAEnrolled(ctx, userModel)
 
-	return nil
+	return &RecoveryCodesResp{RecoveryCodes: codes}, nil
 }
 
 // UnbindMFAReq 解绑MFA请求
@@ -358,6 +532,16 @@ func UnbindMFA(ctx context.Context, req *UnbindMFAReq) error {
 		zapx.ErrorCtx(ctx, "clear mfa secret failed", zap.Error(err))
 		return err
 	}
+	// 仍有安全密钥时保留恢复码
+	records, err := new(model.AdminWebauthnCredential).GetByAdmin(ctx, dbs.Admin, req.TargetUserID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get webauthn credentials failed", zap.Error(err))
+	} else if len(records) == 0 {
+		if err = new(model.AdminRecoveryCode).DeleteByAdmin(ctx, dbs.Admin, req.TargetUserID); err != nil {
+			// 未绑定二次验证时恢复码不会被使用，这里只记录日志
+			zapx.ErrorCtx(ctx, "delete recovery codes failed", zap.Error(err))
+		}
+	}
 
 	// 记录日志
 	zapx.InfoCtx(ctx, "unbind mfa success",
@@ -368,45 +552,36 @@ func UnbindMFA(ctx context.Context, req *UnbindMFAReq) error {
 
 	return nil
 }
-func ValidateLoginIP(ctx context.Context, loginIP string) (bool, error) {
-	systemConf := new(model.SystemConf)
-	err := systemConf.GetByKey(ctx, dbs.System, "ip_whitelist")
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return true, nil
-		}
-		zapx.ErrorCtx(ctx, "get ip whitelist config failed", zap.Error(err))
-		return false, err
-	}
-
-	if systemConf.Val == "" {
-		return true, nil
-	}
 
-	ips := strings.Split(systemConf.Val, ",")
-	for _, ip := range ips {
-		ip = strings.TrimSpace(ip)
-		if ip == "" {
-			continue
-		}
-
-		if isIPMatch(ip, loginIP) {
-			return true, nil
-		}
-	}
-
-	return false, nil
+// ForceLogoutReq 强制下线请求
+type ForceLogoutReq struct {
+	OperatorID   int64 `json:"-"`
+	TargetUserID int64 `json:"target_user_id" binding:"required"`
 }
 
-func isIPMatch(pattern, ip string) bool {
-	if pattern == ip {
-		return true
+// ForceLogout 强制下线指定管理员的全部会话，只有超级管理员可以强制下线超级管理员
+func ForceLogout(ctx context.Context, req *ForceLogoutReq) (int, error) {
+	if req.OperatorID == req.TargetUserID {
+		return 0, errors.New("不能强制下线自己，请使用退出登录")
+	}
+	_, targetUserModel, err := loadManaged(ctx, &ManageAdminReq{
+		OperatorID:   req.OperatorID,
+		TargetUserID: req.TargetUserID,
+	})
+	if err != nil {
+		return 0, err
 	}
 
-	re, err := regexp.Compile(pattern)
+	n, err := session_service.RevokeAll(ctx, req.TargetUserID)
 	if err != nil {
-		return false
+		return 0, err
 	}
 
-	return re.MatchString(ip)
+	zapx.InfoCtx(ctx, "force logout success",
+		zap.Int64("operator_id", req.OperatorID),
+		zap.Int64("target_user_id", req.TargetUserID),
+		zap.String("target_account", targetUserModel.Account),
+		zap.Int("sessions", n))
+
+	return n, nil
 }
@@ -0,0 +1,239 @@
+package admin_service
+
+import (
+	"admin/internal/app/codex"
+	"admin/internal/common/auth"
+	"admin/internal/model"
+	"admin/internal/service/conf_service"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/rdb"
+	"wallet/common-lib/zapx"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 登录防爆破配置
+const (
+	confLoginMaxFailures   = "login_max_failures"    // 单账号连续失败次数上限，达到后锁定账号
+	confLoginIPMaxFailures = "login_ip_max_failures" // 单IP失败次数上限，达到后锁定IP
+	confLoginLockMinutes   = "login_lock_minutes"    // 锁定时长，同时也是失败计数的统计窗口
+	confLoginDelayAfter    = "login_delay_after"     // 连续失败超过该次数后，每次失败需等待的时间翻倍
+)
+
+const (
+	defaultLoginMaxFailures   = 5
+	defaultLoginIPMaxFailures = 20
+	defaultLoginLockMinutes   = 15
+	defaultLoginDelayAfter    = 2
+	maxLoginDelay             = time.Minute
+)
+
+const (
+	loginFailPrefix = "admin.login.fail:" // 失败计数
+	loginLockPrefix = "admin.login.lock:" // 锁定标记
+	loginWaitPrefix = "admin.login.wait:" // 渐进延迟标记
+)
+
+// LoginError 登录失败，Extend中携带锁定状态供前端展示
+type LoginError struct {
+	Code        codex.Code `json:"-"`
+	Msg         string     `json:"-"`
+	Remaining   int        `json:"remaining"`    // 锁定前剩余可尝试次数
+	RetryAfter  int64      `json:"retry_after"`  // 需等待的秒数
+	LockedUntil int64      `json:"locked_until"` // 锁定截止时间戳
+}
+
+func (e *LoginError) Error() string {
+	return e.Msg
+}
+
+type guardConf struct {
+	maxFailures   int
+	ipMaxFailures int
+	lock          time.Duration
+	delayAfter    int
+}
+
+func loadGuardConf(ctx context.Context) *guardConf {
+	return &guardConf{
+		maxFailures:   conf_service.Int(ctx, confLoginMaxFailures, defaultLoginMaxFailures),
+		ipMaxFailures: conf_service.Int(ctx, confLoginIPMaxFailures, defaultLoginIPMaxFailures),
+		lock:          time.Duration(conf_service.Int(ctx, confLoginLockMinutes, defaultLoginLockMinutes)) * time.Minute,
+		delayAfter:    conf_service.Int(ctx, confLoginDelayAfter, defaultLoginDelayAfter),
+	}
+}
+
+func accountKey(prefix, account string) string {
+	return fmt.Sprintf("%saccount:%s", prefix, account)
+}
+
+func ipKey(prefix, ip string) string {
+	return fmt.Sprintf("%sip:%s", prefix, ip)
+}
+
+// checkLoginGuard 登录前检查账号/IP是否被锁定或处于延迟等待中
+func checkLoginGuard(ctx context.Context, account, ip string) error {
+	var (
+		rds = rdb.Client
+		now = time.Now()
+	)
+	locks := []struct {
+		key  string
+		code codex.Code
+	}{
+		{ipKey(loginLockPrefix, ip), codex.TooManyRequest},
+		{accountKey(loginLockPrefix, account), codex.UserFrozen},
+	}
+	for _, l := range locks {
+		ttl, err := rds.PTTL(ctx, l.key).Result()
+		if err != nil {
+			zapx.ErrorCtx(ctx, "read login lock failed", zap.String("key", l.key), zap.Error(err))
+			return err
+		}
+		if ttl > 0 {
+			return &LoginError{
+				Code:        l.code,
+				Msg:         "登录失败次数过多，请稍后再试",
+				RetryAfter:  int64(ttl.Round(time.Second) / time.Second),
+				LockedUntil: now.Add(ttl).Unix(),
+			}
+		}
+	}
+	ttl, err := rds.PTTL(ctx, accountKey(loginWaitPrefix, account)).Result()
+	if err != nil {
+		zapx.ErrorCtx(ctx, "read login wait failed", zap.Error(err))
+		return err
+	}
+	if ttl > 0 {
+		return &LoginError{
+			Code:       codex.TooManyRequest,
+			Msg:        "操作过于频繁，请稍后再试",
+			RetryAfter: int64((ttl + time.Second - 1) / time.Second),
+		}
+	}
+	return nil
+}
+
+// loginFailed 记录一次登录失败，返回带剩余次数或锁定信息的错误
+func loginFailed(ctx context.Context, account, ip, msg string) error {
+	var (
+		rds = rdb.Client
+		gc  = loadGuardConf(ctx)
+		now = time.Now()
+	)
+	accountCnt, err := incrFailure(ctx, accountKey(loginFailPrefix, account), gc.lock)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "incr account login failure failed", zap.Error(err))
+		return errors.New(msg)
+	}
+	ipCnt, err := incrFailure(ctx, ipKey(loginFailPrefix, ip), gc.lock)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "incr ip login failure failed", zap.Error(err))
+		return errors.New(msg)
+	}
+
+	if gc.ipMaxFailures > 0 && ipCnt >= int64(gc.ipMaxFailures) {
+		_ = rds.Set(ctx, ipKey(loginLockPrefix, ip), now.Unix(), gc.lock).Err()
+		zapx.WarnCtx(ctx, "login ip locked", zap.String("ip", ip), zap.Int64("failures", ipCnt))
+	}
+	if gc.maxFailures > 0 && accountCnt >= int64(gc.maxFailures) {
+		_ = rds.Set(ctx, accountKey(loginLockPrefix, account), now.Unix(), gc.lock).Err()
+		_ = rds.Del(ctx, accountKey(loginFailPrefix, account)).Err()
+		zapx.WarnCtx(ctx, "login account locked", zap.String("account", account), zap.String("ip", ip))
+		return &LoginError{
+			Code:        codex.UserFrozen,
+			Msg:         "登录失败次数过多，账号已被临时锁定",
+			RetryAfter:  int64(gc.lock / time.Second),
+			LockedUntil: now.Add(gc.lock).Unix(),
+		}
+	}
+
+	e := &LoginError{
+		Code: codex.WrongPassword,
+		Msg:  msg,
+	}
+	if gc.maxFailures > 0 {
+		e.Remaining = gc.maxFailures - int(accountCnt)
+	}
+	// 渐进延迟: 超过delayAfter次后每次失败的等待时间翻倍
+	if n := int(accountCnt) - gc.delayAfter; n > 0 {
+		delay := maxLoginDelay
+		if n <= 6 {
+			delay = min(time.Duration(1<<(n-1))*time.Second, maxLoginDelay)
+		}
+		_ = rds.Set(ctx, accountKey(loginWaitPrefix, account), now.Unix(), delay).Err()
+		e.RetryAfter = int64(delay / time.Second)
+	}
+	return e
+}
+
+func incrFailure(ctx context.Context, key string, window time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := rdb.Client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		incr = p.Incr(ctx, key)
+		p.ExpireNX(ctx, key, window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// loginSucceeded 登录成功后清除账号的失败计数
+func loginSucceeded(ctx context.Context, account string) {
+	err := rdb.Client.Del(ctx, accountKey(loginFailPrefix, account), accountKey(loginWaitPrefix, account)).Err()
+	if err != nil {
+		zapx.ErrorCtx(ctx, "clear login failures failed", zap.String("account", account), zap.Error(err))
+	}
+}
+
+// UnlockLoginReq 解除登录锁定请求
+type UnlockLoginReq struct {
+	OperatorID int64  `json:"-"`
+	Account    string `json:"account"`
+	IP         string `json:"ip"`
+}
+
+// UnlockLogin 解除账号和/或IP的登录锁定（仅超级管理员可操作）
+func UnlockLogin(ctx context.Context, req *UnlockLoginReq) error {
+	if req.Account == "" && req.IP == "" {
+		return errors.New("账号和IP不能同时为空")
+	}
+	operatorModel := new(model.Admin)
+	if err := operatorModel.GetByID(ctx, dbs.Admin, req.OperatorID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("操作者不存在")
+		}
+		zapx.ErrorCtx(ctx, "get operator by id failed", zap.Error(err))
+		return err
+	}
+	if operatorModel.RoleID != auth.SuperAdminRoleID {
+		return errors.New("仅超级管理员可以解除登录锁定")
+	}
+	keys := make([]string, 0, 5)
+	if req.Account != "" {
+		keys = append(keys,
+			accountKey(loginLockPrefix, req.Account),
+			accountKey(loginFailPrefix, req.Account),
+			accountKey(loginWaitPrefix, req.Account))
+	}
+	if req.IP != "" {
+		keys = append(keys, ipKey(loginLockPrefix, req.IP), ipKey(loginFailPrefix, req.IP))
+	}
+	if err := rdb.Client.Del(ctx, keys...).Err(); err != nil {
+		zapx.ErrorCtx(ctx, "unlock login failed", zap.Error(err))
+		return err
+	}
+	zapx.InfoCtx(ctx, "unlock login success",
+		zap.Int64("operator_id", req.OperatorID),
+		zap.String("account", req.Account),
+		zap.String("ip", req.IP))
+	return nil
+}
@@ -0,0 +1,193 @@
+package admin_service
+
+import (
+	"admin/internal/model"
+	"admin/internal/service/conf_service"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/dto/req_dto"
+	"wallet/common-lib/natsx"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+)
+
+const (
+	confLoginAlertFailures    = "login_alert_failures" // 连续失败达到该次数时发送告警，0表示不告警
+	defaultLoginAlertFailures = 3
+	loginAlertWindow          = 24 * time.Hour // 只统计该时间内的连续失败
+
+	// 可疑登录告警的NATS主题，由值班群的机器人订阅
+	loginAlertSubject = "admin.security.login"
+
+	maxUserAgentLength = 255
+	maxReasonLength    = 100
+)
+
+// 可疑登录告警类型
+const (
+	AlertNewIP              = "new_ip"               // 从未登录成功过的IP登录成功
+	AlertRepeatedFailures   = "repeated_failures"    // 连续登录失败
+	AlertLoginAfterFailures = "login_after_failures" // 连续失败后登录成功
+)
+
+// loginEvent 一次登录尝试，登录过程中逐步补全
+type loginEvent struct {
+	AdminID   int64
+	Account   string
+	IP        string
+	UserAgent string
+	MFA       string // 通过校验的二次验证方式
+}
+
+// LoginAlert 可疑登录告警
+type LoginAlert struct {
+	Type      string `json:"type"`
+	AdminID   int64  `json:"admin_id"`
+	Account   string `json:"account"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	Failures  int64  `json:"failures,omitempty"`
+	At        int64  `json:"at"`
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// recordLogin 写入登录记录并按需发送可疑登录告警，失败不影响登录流程
+func recordLogin(ctx context.Context, ev *loginEvent, loginErr error) {
+	// 需要二次验证时客户端会带上验证信息重新登录，这一步不单独记录
+	var mfaErr *MFARequiredError
+	if errors.As(loginErr, &mfaErr) {
+		return
+	}
+
+	l := &model.AdminLoginLog{
+		AdminID:   ev.AdminID,
+		Account:   ev.Account,
+		IP:        ev.IP,
+		UserAgent: truncate(ev.UserAgent, maxUserAgentLength),
+		MFA:       ev.MFA,
+	}
+	var newIP bool
+	if loginErr == nil {
+		l.Success = 1
+		var err error
+		if newIP, err = isNewIP(ctx, ev.AdminID, ev.IP); err != nil {
+			zapx.ErrorCtx(ctx, "check login ip history failed", zap.Int64("admin_id", ev.AdminID), zap.Error(err))
+		}
+	} else {
+		l.Reason = truncate(loginErr.Error(), maxReasonLength)
+	}
+	if err := l.Create(ctx, dbs.Admin); err != nil {
+		zapx.ErrorCtx(ctx, "create login log failed", zap.String("account", ev.Account), zap.Error(err))
+		return
+	}
+
+	threshold := int64(conf_service.Int(ctx, confLoginAlertFailures, defaultLoginAlertFailures))
+	if newIP {
+		publishLoginAlert(ctx, ev, AlertNewIP, 0)
+	}
+	if threshold <= 0 {
+		return
+	}
+	since := time.Now().Add(-loginAlertWindow)
+	if loginErr == nil {
+		failures, err := l.CountFailures(ctx, dbs.Admin, ev.Account, since, l.ID)
+		if err != nil {
+			zapx.ErrorCtx(ctx, "count login failures failed", zap.String("account", ev.Account), zap.Error(err))
+			return
+		}
+		if failures >= threshold {
+			publishLoginAlert(ctx, ev, AlertLoginAfterFailures, failures)
+		}
+		return
+	}
+	failures, err := l.CountFailures(ctx, dbs.Admin, ev.Account, since, 0)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "count login failures failed", zap.String("account", ev.Account), zap.Error(err))
+		return
+	}
+	// 每达到一次阈值告警一次，避免持续爆破时刷屏
+	if failures > 0 && failures%threshold == 0 {
+		publishLoginAlert(ctx, ev, AlertRepeatedFailures, failures)
+	}
+}
+
+// isNewIP 管理员以前登录成功过，但从未从该IP登录成功；首次登录不视为新IP
+func isNewIP(ctx context.Context, adminID int64, ip string) (bool, error) {
+	l := new(model.AdminLoginLog)
+	seen, err := l.HasSucceeded(ctx, dbs.Admin, adminID, ip)
+	if err != nil || seen {
+		return false, err
+	}
+	return l.HasSucceeded(ctx, dbs.Admin, adminID, "")
+}
+
+func publishLoginAlert(ctx context.Context, ev *loginEvent, typ string, failures int64) {
+	data, err := json.Marshal(&LoginAlert{
+		Type:      typ,
+		AdminID:   ev.AdminID,
+		Account:   ev.Account,
+		IP:        ev.IP,
+		UserAgent: ev.UserAgent,
+		Failures:  failures,
+		At:        time.Now().Unix(),
+	})
+	if err != nil {
+		zapx.ErrorCtx(ctx, "marshal login alert failed", zap.Error(err))
+		return
+	}
+	zapx.WarnCtx(ctx, "suspicious login",
+		zap.String("type", typ),
+		zap.String("account", ev.Account),
+		zap.String("ip", ev.IP),
+		zap.Int64("failures", failures))
+	if err = natsx.Publish(ctx, loginAlertSubject, data); err != nil {
+		zapx.ErrorCtx(ctx, "publish login alert failed", zap.String("type", typ), zap.Error(err))
+	}
+}
+
+// LoginLogsReq 登录记录列表请求
+type LoginLogsReq struct {
+	req_dto.PageArgs
+	AdminID int64  `json:"admin_id"` // 管理员筛选
+	Account string `json:"account"`  // 账号筛选，可查询不存在的账号的登录尝试
+	Success *int8  `json:"success"`  // 1=成功 0=失败，不填为全部
+	IP      string `json:"ip"`
+	From    int64  `json:"from"` // 开始时间（时间戳）
+	To      int64  `json:"to"`   // 结束时间（时间戳）
+}
+
+// LoginLogs 分页查询登录记录
+func LoginLogs(ctx context.Context, req *LoginLogsReq) ([]*model.AdminLoginLog, int64, error) {
+	req.PageArgs.Init()
+	f := &model.LoginLogFilter{
+		AdminID: req.AdminID,
+		Account: req.Account,
+		Success: req.Success,
+		IP:      req.IP,
+	}
+	if req.From > 0 {
+		t := time.Unix(req.From, 0)
+		f.From = &t
+	}
+	if req.To > 0 {
+		t := time.Unix(req.To, 0)
+		f.To = &t
+	}
+	list, total, err := new(model.AdminLoginLog).GetList(ctx, dbs.Admin, req.Page, req.Size, f)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "loginLog.GetList failed", zap.Error(err))
+		return nil, 0, err
+	}
+	return list, total, nil
+}
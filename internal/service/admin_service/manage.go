@@ -0,0 +1,279 @@
+package admin_service
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/model"
+	"admin/internal/service/perm_service"
+	"admin/internal/service/session_service"
+	"context"
+	"errors"
+	"time"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/dto/req_dto"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AdminItem 管理员列表/详情项
+type AdminItem struct {
+	ID            int64      `json:"id"`
+	Account       string     `json:"account"`
+	RoleID        int        `json:"role_id"`
+	RoleName      string     `json:"role_name"`
+	Status        int        `json:"status"`
+	MfaBound      bool       `json:"mfa_bound"`
+	MustChangePwd bool       `json:"must_change_pwd"`
+	IsService     bool       `json:"is_service"`
+	LastLoginAt   *time.Time `json:"last_login_at"`
+	LastLoginIP   string     `json:"last_login_ip"`
+	PwdChangedAt  *time.Time `json:"pwd_changed_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func toAdminItem(a *model.Admin, roleNames map[int]string) *AdminItem {
+	return &AdminItem{
+		ID:            a.ID,
+		Account:       a.Account,
+		RoleID:        a.RoleID,
+		RoleName:      roleNames[a.RoleID],
+		Status:        a.Status,
+		MfaBound:      len(a.MfaSecret) > 0,
+		MustChangePwd: a.MustChangePwd == 1,
+		IsService:     a.IsService == 1,
+		LastLoginAt:   a.LastLoginAt,
+		LastLoginIP:   a.LastLoginIP,
+		PwdChangedAt:  a.PwdChangedAt,
+		CreatedAt:     a.CreatedAt,
+	}
+}
+
+func roleNameMap(ctx context.Context) (map[int]string, error) {
+	roles, err := new(model.Role).GetAll(ctx, dbs.Admin)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get roles failed", zap.Error(err))
+		return nil, err
+	}
+	m := make(map[int]string, len(roles))
+	for _, r := range roles {
+		m[r.ID] = r.Name
+	}
+	return m, nil
+}
+
+// ListAdminsReq 管理员列表请求
+type ListAdminsReq struct {
+	req_dto.PageArgs
+	Account       string `json:"account"`         // 账号筛选
+	RoleID        int    `json:"role_id"`         // 角色筛选
+	Status        *int   `json:"status"`          // 状态筛选 0=禁用，1=启用
+	MfaBound      *bool  `json:"mfa_bound"`       // 是否绑定谷歌验证器
+	LastLoginFrom int64  `json:"last_login_from"` // 最近登录时间起（时间戳）
+	LastLoginTo   int64  `json:"last_login_to"`   // 最近登录时间止（时间戳）
+}
+
+type ListAdminsResp struct {
+	List  []*AdminItem `json:"list"`
+	Total int64        `json:"total"`
+}
+
+// ListAdmins 分页查询管理员列表
+func ListAdmins(ctx context.Context, req *ListAdminsReq) (*ListAdminsResp, error) {
+	req.PageArgs.Init()
+
+	f := &model.AdminFilter{
+		Account:  req.Account,
+		RoleID:   req.RoleID,
+		Status:   req.Status,
+		MfaBound: req.MfaBound,
+	}
+	if req.LastLoginFrom > 0 {
+		t := time.Unix(req.LastLoginFrom, 0)
+		f.LastLoginFrom = &t
+	}
+	if req.LastLoginTo > 0 {
+		t := time.Unix(req.LastLoginTo, 0)
+		f.LastLoginTo = &t
+	}
+
+	list, total, err := new(model.Admin).GetList(ctx, dbs.Admin, req.Page, req.Size, f)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "admin.GetList failed", zap.Error(err))
+		return nil, err
+	}
+	roleNames, err := roleNameMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*AdminItem, 0, len(list))
+	for _, a := range list {
+		items = append(items, toAdminItem(a, roleNames))
+	}
+	return &ListAdminsResp{List: items, Total: total}, nil
+}
+
+// AdminDetailResp 管理员详情
+type AdminDetailResp struct {
+	*AdminItem
+	Sessions int        `json:"sessions"` // 当前有效会话数
+	MFA      *MFAStatus `json:"mfa"`      // 二次验证状态
+}
+
+// AdminDetail 管理员详情
+func AdminDetail(ctx context.Context, userID int64) (*AdminDetailResp, error) {
+	userModel := new(model.Admin)
+	if err := userModel.GetByID(ctx, dbs.Admin, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		zapx.ErrorCtx(ctx, "get user by id failed", zap.Error(err))
+		return nil, err
+	}
+	roleNames, err := roleNameMap(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := session_service.List(ctx, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := mfaStatus(ctx, userModel)
+	if err != nil {
+		return nil, err
+	}
+	return &AdminDetailResp{
+		AdminItem: toAdminItem(userModel, roleNames),
+		Sessions:  len(sessions),
+		MFA:       mfa,
+	}, nil
+}
+
+// ManageAdminReq 管理员操作请求
+type ManageAdminReq struct {
+	OperatorID   int64 `json:"-"`
+	TargetUserID int64 `json:"target_user_id" binding:"required"`
+	RoleID       int   `json:"role_id"` // 仅修改角色时使用
+}
+
+// loadManaged 查询操作者与目标管理员，并校验操作者是否有权管理目标
+func loadManaged(ctx context.Context, req *ManageAdminReq) (*model.Admin, *model.Admin, error) {
+	if req.OperatorID == req.TargetUserID {
+		return nil, nil, errors.New("不能对自己进行该操作")
+	}
+	operatorModel := new(model.Admin)
+	if err := operatorModel.GetByID(ctx, dbs.Admin, req.OperatorID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("操作者不存在")
+		}
+		zapx.ErrorCtx(ctx, "get operator by id failed", zap.Error(err))
+		return nil, nil, err
+	}
+	targetUserModel := new(model.Admin)
+	if err := targetUserModel.GetByID(ctx, dbs.Admin, req.TargetUserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("目标用户不存在")
+		}
+		zapx.ErrorCtx(ctx, "get target user by id failed", zap.Error(err))
+		return nil, nil, err
+	}
+	// 只有超级管理员可以管理超级管理员
+	if targetUserModel.RoleID == auth.SuperAdminRoleID && operatorModel.RoleID != auth.SuperAdminRoleID {
+		return nil, nil, errors.New("仅超级管理员可以管理超级管理员")
+	}
+	return operatorModel, targetUserModel, nil
+}
+
+// UpdateAdminRole 修改管理员角色
+func UpdateAdminRole(ctx context.Context, req *ManageAdminReq) error {
+	operatorModel, targetUserModel, err := loadManaged(ctx, req)
+	if err != nil {
+		return err
+	}
+	exists, err := new(model.Role).Exists(ctx, dbs.Admin, req.RoleID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "check role exists failed", zap.Error(err))
+		return err
+	}
+	if !exists {
+		return errors.New("角色不存在")
+	}
+	if err = checkRoleGrant(ctx, operatorModel.ID, operatorModel.RoleID, req.RoleID); err != nil {
+		return err
+	}
+	if targetUserModel.RoleID == req.RoleID {
+		return nil
+	}
+
+	if err = targetUserModel.Update(ctx, dbs.Admin, req.TargetUserID, map[string]any{"role_id": req.RoleID}); err != nil {
+		zapx.ErrorCtx(ctx, "update admin role failed", zap.Error(err))
+		return err
+	}
+	afterAdminChanged(ctx, req.TargetUserID)
+
+	zapx.InfoCtx(ctx, "update admin role success",
+		zap.Int64("operator_id", req.OperatorID),
+		zap.Int64("target_user_id", req.TargetUserID),
+		zap.String("target_account", targetUserModel.Account),
+		zap.Int("old_role_id", targetUserModel.RoleID),
+		zap.Int("role_id", req.RoleID))
+	return nil
+}
+
+// SetAdminStatus 启用/禁用管理员，禁用后其会话立即失效
+func SetAdminStatus(ctx context.Context, req *ManageAdminReq, enable bool) error {
+	_, targetUserModel, err := loadManaged(ctx, req)
+	if err != nil {
+		return err
+	}
+	status := 0
+	if enable {
+		status = 1
+	}
+	if targetUserModel.Status == status {
+		return nil
+	}
+
+	if err = targetUserModel.Update(ctx, dbs.Admin, req.TargetUserID, map[string]any{"status": status}); err != nil {
+		zapx.ErrorCtx(ctx, "update admin status failed", zap.Error(err))
+		return err
+	}
+	afterAdminChanged(ctx, req.TargetUserID)
+
+	zapx.InfoCtx(ctx, "update admin status success",
+		zap.Int64("operator_id", req.OperatorID),
+		zap.Int64("target_user_id", req.TargetUserID),
+		zap.String("target_account", targetUserModel.Account),
+		zap.Int("status", status))
+	return nil
+}
+
+// DeleteAdmin 软删除管理员，其会话立即失效
+func DeleteAdmin(ctx context.Context, req *ManageAdminReq) error {
+	_, targetUserModel, err := loadManaged(ctx, req)
+	if err != nil {
+		return err
+	}
+	if err = targetUserModel.SoftDelete(ctx, dbs.Admin, req.TargetUserID); err != nil {
+		zapx.ErrorCtx(ctx, "soft delete admin failed", zap.Error(err))
+		return err
+	}
+	afterAdminChanged(ctx, req.TargetUserID)
+
+	zapx.InfoCtx(ctx, "delete admin success",
+		zap.Int64("operator_id", req.OperatorID),
+		zap.Int64("target_user_id", req.TargetUserID),
+		zap.String("target_account", targetUserModel.Account))
+	return nil
+}
+
+// afterAdminChanged 管理员状态或角色变更后同步其会话，角色变更后权限也随之变化
+func afterAdminChanged(ctx context.Context, userID int64) {
+	if err := session_service.Sync(ctx, userID); err != nil {
+		// 中间件定期核对会话时也会同步，这里只记录日志
+		zapx.ErrorCtx(ctx, "sync sessions failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+	if err := perm_service.InvalidateCache(ctx, userID); err != nil {
+		zapx.ErrorCtx(ctx, "invalidate perm cache failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+}
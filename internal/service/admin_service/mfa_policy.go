@@ -0,0 +1,123 @@
+package admin_service
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/model"
+	"admin/internal/service/conf_service"
+	"admin/internal/service/session_service"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/zapx"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 强制MFA配置
+const (
+	confMfaRequiredRoles = "mfa_required_roles"     // 必须绑定谷歌验证器的角色ID，逗号分隔
+	confMfaEnrollGrace   = "mfa_enroll_grace_hours" // 新账号绑定谷歌验证器的宽限期(小时)，从账号创建时间起算
+
+	defaultMfaEnrollGrace = 72
+)
+
+// 二次验证方式
+const (
+	mfaFactorTotp     = "totp"
+	mfaFactorWebauthn = "webauthn"
+	mfaFactorRecovery = "recovery_code"
+)
+
+// MFARequiredError 密码校验通过但未提供二次验证，携带可用的验证方式
+type MFARequiredError struct {
+	Factors  []string                      `json:"factors"`            // 可用的验证方式
+	WebAuthn *protocol.CredentialAssertion `json:"webauthn,omitempty"` // 浏览器navigator.credentials.get所需参数
+}
+
+func (e *MFARequiredError) Error() string {
+	return "请完成二次验证"
+}
+
+// mfaRequired 角色是否必须绑定谷歌验证器
+func mfaRequired(ctx context.Context, roleID int) bool {
+	for _, s := range strings.Split(conf_service.String(ctx, confMfaRequiredRoles, ""), ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(s))
+		if err == nil && id == roleID {
+			return true
+		}
+	}
+	return false
+}
+
+// mfaEnrollDeadline 返回未绑定二次验证的管理员必须完成绑定的截止时间，不需要绑定时返回零值
+func mfaEnrollDeadline(ctx context.Context, userModel *model.Admin, enrolled bool) time.Time {
+	if enrolled || !mfaRequired(ctx, userModel.RoleID) {
+		return time.Time{}
+	}
+	grace := max(conf_service.Int(ctx, confMfaEnrollGrace, defaultMfaEnrollGrace), 0)
+	return userModel.CreatedAt.Add(time.Duration(grace) * time.Hour)
+}
+
+// afterMFAEnrolled 绑定任一二次验证方式后，清除重新绑定要求并解除会话的绑定限制
+func afterMFAEnrolled(ctx context.Context, userModel *model.Admin) {
+	if userModel.MfaReenroll == 1 {
+		if err := userModel.Update(ctx, dbs.Admin, userModel.ID, map[string]any{"mfa_reenroll": 0}); err != nil {
+			zapx.ErrorCtx(ctx, "clear mfa reenroll failed", zap.Int64("user_id", userModel.ID), zap.Error(err))
+		}
+		clearEnrollToken(ctx, userModel.ID)
+	}
+	if err := session_service.ClearScope(ctx, userModel.ID, auth.ScopeMFAEnroll); err != nil {
+		// 重新登录即可获得完整会话，这里只记录日志
+		zapx.ErrorCtx(ctx, "clear mfa enroll scope failed", zap.Int64("user_id", userModel.ID), zap.Error(err))
+	}
+}
+
+// MFAStatus 管理员二次验证状态
+type MFAStatus struct {
+	Totp              bool                      `json:"totp"`                // 是否绑定谷歌验证器
+	WebAuthn          []*WebauthnCredentialItem `json:"webauthn"`            // 已注册的安全密钥
+	RecoveryCodesLeft int64                     `json:"recovery_codes_left"` // 剩余恢复码数量
+	Reenroll          bool                      `json:"reenroll"`            // 是否被重置，需要重新绑定
+	Required          bool                      `json:"required"`            // 角色是否要求二次验证
+	EnrollBy          int64                     `json:"enroll_by,omitempty"` // 未绑定时的绑定截止时间戳
+}
+
+func mfaStatus(ctx context.Context, userModel *model.Admin) (*MFAStatus, error) {
+	records, err := new(model.AdminWebauthnCredential).GetByAdmin(ctx, dbs.Admin, userModel.ID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get webauthn credentials failed", zap.Int64("user_id", userModel.ID), zap.Error(err))
+		return nil, err
+	}
+	left, err := new(model.AdminRecoveryCode).CountUnused(ctx, dbs.Admin, userModel.ID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "count recovery codes failed", zap.Int64("user_id", userModel.ID), zap.Error(err))
+		return nil, err
+	}
+	totp := len(userModel.MfaSecret) > 0
+	return &MFAStatus{
+		Totp:              totp,
+		WebAuthn:          toWebauthnItems(records),
+		RecoveryCodesLeft: left,
+		Reenroll:          userModel.MfaReenroll == 1,
+		Required:          mfaRequired(ctx, userModel.RoleID),
+		EnrollBy:          unixOrZero(mfaEnrollDeadline(ctx, userModel, totp || len(records) > 0)),
+	}, nil
+}
+
+// GetMFAStatus 查询管理员的二次验证状态
+func GetMFAStatus(ctx context.Context, userID int64) (*MFAStatus, error) {
+	userModel := new(model.Admin)
+	if err := userModel.GetByID(ctx, dbs.Admin, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		zapx.ErrorCtx(ctx, "get user by id failed", zap.Error(err))
+		return nil, err
+	}
+	return mfaStatus(ctx, userModel)
+}
@@ -0,0 +1,139 @@
+package admin_service
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/model"
+	"admin/internal/service/conf_service"
+	"admin/internal/service/session_service"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/rdb"
+	"wallet/common-lib/zapx"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	confMfaEnrollTokenHours    = "mfa_enroll_token_hours" // 重新绑定令牌有效期(小时)
+	defaultMfaEnrollTokenHours = 24
+
+	mfaEnrollTokenPrefix = "admin.mfa.enroll:" // 重新绑定令牌哈希
+)
+
+func mfaEnrollTokenKey(userID int64) string {
+	return fmt.Sprintf("%s%d", mfaEnrollTokenPrefix, userID)
+}
+
+func hashEnrollToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ResetMFAReq 重置MFA请求
+type ResetMFAReq struct {
+	OperatorID   int64 `json:"-"`
+	TargetUserID int64 `json:"target_user_id" binding:"required"`
+}
+
+// ResetMFAResp 重置MFA响应
+type ResetMFAResp struct {
+	EnrollToken string `json:"enroll_token"` // 重新绑定令牌，仅返回这一次，需线下交给目标管理员
+	ExpiresAt   int64  `json:"expires_at"`   // 令牌过期时间戳
+}
+
+// ResetMFA 解绑目标管理员的谷歌验证器和安全密钥，并签发重新绑定令牌（仅超级管理员可操作）
+// 目标管理员下次登录时须用令牌代替动态码，且只能获得绑定谷歌验证器的受限会话
+func ResetMFA(ctx context.Context, req *ResetMFAReq) (*ResetMFAResp, error) {
+	operatorModel := new(model.Admin)
+	if err := operatorModel.GetByID(ctx, dbs.Admin, req.OperatorID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("操作者不存在")
+		}
+		zapx.ErrorCtx(ctx, "get operator by id failed", zap.Error(err))
+		return nil, err
+	}
+	if operatorModel.RoleID != auth.SuperAdminRoleID {
+		return nil, errors.New("仅超级管理员可以重置谷歌验证器")
+	}
+	if req.OperatorID == req.TargetUserID {
+		return nil, errors.New("不能重置自己的谷歌验证器")
+	}
+
+	targetUserModel := new(model.Admin)
+	if err := targetUserModel.GetByID(ctx, dbs.Admin, req.TargetUserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("目标用户不存在")
+		}
+		zapx.ErrorCtx(ctx, "get target user by id failed", zap.Error(err))
+		return nil, err
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		zapx.ErrorCtx(ctx, "generate enroll token failed", zap.Error(err))
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	ttl := time.Duration(max(conf_service.Int(ctx, confMfaEnrollTokenHours, defaultMfaEnrollTokenHours), 1)) * time.Hour
+	if err := rdb.Client.Set(ctx, mfaEnrollTokenKey(req.TargetUserID), hashEnrollToken(token), ttl).Err(); err != nil {
+		zapx.ErrorCtx(ctx, "save enroll token failed", zap.Error(err))
+		return nil, err
+	}
+
+	if err := targetUserModel.ResetMfa(ctx, dbs.Admin, req.TargetUserID); err != nil {
+		zapx.ErrorCtx(ctx, "reset mfa failed", zap.Error(err))
+		return nil, err
+	}
+	if err := new(model.AdminWebauthnCredential).DeleteByAdmin(ctx, dbs.Admin, req.TargetUserID); err != nil {
+		zapx.ErrorCtx(ctx, "delete webauthn credentials failed", zap.Error(err))
+		return nil, err
+	}
+	if err := new(model.AdminRecoveryCode).DeleteByAdmin(ctx, dbs.Admin, req.TargetUserID); err != nil {
+		// 未绑定验证器时恢复码不会被使用，这里只记录日志
+		zapx.ErrorCtx(ctx, "delete recovery codes failed", zap.Error(err))
+	}
+	if _, err := session_service.RevokeAll(ctx, req.TargetUserID); err != nil {
+		zapx.ErrorCtx(ctx, "revoke sessions after mfa reset failed", zap.Int64("user_id", req.TargetUserID), zap.Error(err))
+	}
+
+	zapx.InfoCtx(ctx, "reset mfa success",
+		zap.Int64("operator_id", req.OperatorID),
+		zap.String("operator_account", operatorModel.Account),
+		zap.Int64("target_user_id", req.TargetUserID),
+		zap.String("target_account", targetUserModel.Account))
+
+	return &ResetMFAResp{
+		EnrollToken: token,
+		ExpiresAt:   time.Now().Add(ttl).Unix(),
+	}, nil
+}
+
+// checkEnrollToken 校验重新绑定令牌，令牌在绑定完成前可重复用于登录
+func checkEnrollToken(ctx context.Context, userID int64, token string) (bool, error) {
+	hash, err := rdb.Client.Get(ctx, mfaEnrollTokenKey(userID)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		zapx.ErrorCtx(ctx, "get enroll token failed", zap.Error(err))
+		return false, err
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(hashEnrollToken(token))) == 1, nil
+}
+
+// clearEnrollToken 绑定完成后作废重新绑定令牌
+func clearEnrollToken(ctx context.Context, userID int64) {
+	if err := rdb.Client.Del(ctx, mfaEnrollTokenKey(userID)).Err(); err != nil {
+		zapx.ErrorCtx(ctx, "delete enroll token failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+}
@@ -0,0 +1,312 @@
+package admin_service
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/model"
+	"admin/internal/service/conf_service"
+	"admin/internal/service/session_service"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+	"unicode"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/utils/bcryptx"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 密码策略配置
+const (
+	confPwdMinLength  = "pwd_min_length"        // 最小长度
+	confPwdMinClasses = "pwd_min_classes"       // 至少包含的字符类别数(大写/小写/数字/符号)
+	confPwdBanned     = "pwd_banned"            // 禁用密码，逗号分隔，不区分大小写
+	confPwdExpireDays = "pwd_expire_days"       // 密码有效期(天)，0表示不过期
+	confPwdGraceDays  = "pwd_expire_grace_days" // 密码过期后仍可登录修改密码的宽限期(天)
+	confPwdHistory    = "pwd_history_count"     // 不可重复使用的最近密码数
+)
+
+const (
+	defaultPwdMinLength  = 8
+	defaultPwdMinClasses = 3
+	defaultPwdExpireDays = 90
+	defaultPwdGraceDays  = 7
+	defaultPwdHistory    = 5
+	maxPwdLength         = 72 // bcrypt只取前72字节
+	oneTimePwdLength     = 16
+)
+
+// WeakPasswordError 密码不符合密码策略
+type WeakPasswordError struct {
+	Msg string
+}
+
+func (e *WeakPasswordError) Error() string {
+	return e.Msg
+}
+
+// ValidatePassword 按密码策略校验密码
+func ValidatePassword(ctx context.Context, account, password string) error {
+	minLength := conf_service.Int(ctx, confPwdMinLength, defaultPwdMinLength)
+	if len(password) < minLength {
+		return &WeakPasswordError{Msg: fmt.Sprintf("密码长度不能少于%d位", minLength)}
+	}
+	if len(password) > maxPwdLength {
+		return &WeakPasswordError{Msg: fmt.Sprintf("密码长度不能超过%d位", maxPwdLength)}
+	}
+
+	minClasses := conf_service.Int(ctx, confPwdMinClasses, defaultPwdMinClasses)
+	if n := charClasses(password); n < minClasses {
+		return &WeakPasswordError{Msg: fmt.Sprintf("密码需至少包含大写字母、小写字母、数字、符号中的%d类", minClasses)}
+	}
+
+	lower := strings.ToLower(password)
+	if account != "" && strings.Contains(lower, strings.ToLower(account)) {
+		return &WeakPasswordError{Msg: "密码不能包含账号"}
+	}
+	for _, banned := range strings.Split(conf_service.String(ctx, confPwdBanned, ""), ",") {
+		banned = strings.TrimSpace(banned)
+		if banned != "" && lower == strings.ToLower(banned) {
+			return &WeakPasswordError{Msg: "密码过于简单，请更换"}
+		}
+	}
+	return nil
+}
+
+func charClasses(s string) int {
+	var upper, lower, digit, symbol int
+	for _, r := range s {
+		switch {
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	return upper + lower + digit + symbol
+}
+
+// ChangePasswordReq 修改密码请求
+type ChangePasswordReq struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+	TotpCode    string `json:"totp_code"` // 已绑定谷歌验证器时必填
+}
+
+// ChangePassword 修改自己的密码，成功后注销全部会话
+func ChangePassword(ctx context.Context, userID int64, req *ChangePasswordReq) error {
+	userModel := new(model.Admin)
+	if err := userModel.GetByID(ctx, dbs.Admin, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("用户不存在")
+		}
+		zapx.ErrorCtx(ctx, "get user by id failed", zap.Error(err))
+		return err
+	}
+
+	if !bcryptx.Check(userModel.Password, req.OldPassword) {
+		return errors.New("原密码错误")
+	}
+	if len(userModel.MfaSecret) > 0 {
+		if req.TotpCode == "" {
+			return errors.New("请输入谷歌验证器动态码")
+		}
+		ok, err := checkTOTP(ctx, userModel, req.TotpCode)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("谷歌验证器动态码错误")
+		}
+	}
+	if err := ValidatePassword(ctx, userModel.Account, req.NewPassword); err != nil {
+		return err
+	}
+	if err := checkPasswordReuse(ctx, userModel, req.NewPassword); err != nil {
+		return err
+	}
+
+	if err := setPassword(ctx, userModel, req.NewPassword, false); err != nil {
+		return err
+	}
+	zapx.InfoCtx(ctx, "change password success", zap.Int64("user_id", userID), zap.String("account", userModel.Account))
+	return nil
+}
+
+// ResetPasswordReq 重置密码请求
+type ResetPasswordReq struct {
+	OperatorID   int64 `json:"-"`
+	TargetUserID int64 `json:"target_user_id" binding:"required"`
+}
+
+// ResetPasswordResp 重置密码响应
+type ResetPasswordResp struct {
+	Password string `json:"password"` // 一次性密码，仅返回这一次
+}
+
+// ResetPassword 重置管理员密码为一次性密码（仅超级管理员可操作），目标下次登录必须修改密码
+func ResetPassword(ctx context.Context, req *ResetPasswordReq) (*ResetPasswordResp, error) {
+	operatorModel := new(model.Admin)
+	if err := operatorModel.GetByID(ctx, dbs.Admin, req.OperatorID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("操作者不存在")
+		}
+		zapx.ErrorCtx(ctx, "get operator by id failed", zap.Error(err))
+		return nil, err
+	}
+	if operatorModel.RoleID != auth.SuperAdminRoleID {
+		return nil, errors.New("仅超级管理员可以重置密码")
+	}
+	if req.OperatorID == req.TargetUserID {
+		return nil, errors.New("不能重置自己的密码，请使用修改密码")
+	}
+
+	targetUserModel := new(model.Admin)
+	if err := targetUserModel.GetByID(ctx, dbs.Admin, req.TargetUserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("目标用户不存在")
+		}
+		zapx.ErrorCtx(ctx, "get target user by id failed", zap.Error(err))
+		return nil, err
+	}
+
+	password, err := oneTimePassword()
+	if err != nil {
+		zapx.ErrorCtx(ctx, "generate one-time password failed", zap.Error(err))
+		return nil, err
+	}
+	if err = setPassword(ctx, targetUserModel, password, true); err != nil {
+		return nil, err
+	}
+
+	zapx.InfoCtx(ctx, "reset password success",
+		zap.Int64("operator_id", req.OperatorID),
+		zap.String("operator_account", operatorModel.Account),
+		zap.Int64("target_user_id", req.TargetUserID),
+		zap.String("target_account", targetUserModel.Account))
+
+	return &ResetPasswordResp{Password: password}, nil
+}
+
+// checkPasswordReuse 新密码不能与当前密码及最近使用过的密码相同
+func checkPasswordReuse(ctx context.Context, userModel *model.Admin, password string) error {
+	if bcryptx.Check(userModel.Password, password) {
+		return &WeakPasswordError{Msg: "新密码不能与原密码相同"}
+	}
+	n := conf_service.Int(ctx, confPwdHistory, defaultPwdHistory)
+	if n <= 0 {
+		return nil
+	}
+	history, err := new(model.AdminPasswordHistory).GetRecent(ctx, dbs.Admin, userModel.ID, n)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get password history failed", zap.Error(err))
+		return err
+	}
+	for _, h := range history {
+		if bcryptx.Check(h.Password, password) {
+			return &WeakPasswordError{Msg: fmt.Sprintf("新密码不能与最近%d次使用过的密码相同", n)}
+		}
+	}
+	return nil
+}
+
+const (
+	pwdValid        = iota
+	pwdExpiredGrace // 已过期，宽限期内只能修改密码
+	pwdExpired      // 已过期且超过宽限期
+)
+
+// passwordExpiry 判断管理员密码是否已过期，没有密码修改时间的（启用密码有效期之前的账号）从首次登录开始计算
+func passwordExpiry(ctx context.Context, userModel *model.Admin, now time.Time) int {
+	days := conf_service.Int(ctx, confPwdExpireDays, defaultPwdExpireDays)
+	if days <= 0 || userModel.PwdChangedAt == nil {
+		return pwdValid
+	}
+	expireAt := userModel.PwdChangedAt.AddDate(0, 0, days)
+	if now.Before(expireAt) {
+		return pwdValid
+	}
+	grace := conf_service.Int(ctx, confPwdGraceDays, defaultPwdGraceDays)
+	if now.Before(expireAt.AddDate(0, 0, grace)) {
+		return pwdExpiredGrace
+	}
+	return pwdExpired
+}
+
+// setPassword 保存新密码并注销该管理员的全部会话，一次性密码不计入历史密码
+func setPassword(ctx context.Context, userModel *model.Admin, password string, mustChange bool) error {
+	hashedPassword, err := bcryptx.Hash(password)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "hash password failed", zap.Error(err))
+		return err
+	}
+	if err = userModel.UpdatePassword(ctx, dbs.Admin, userModel.ID, hashedPassword, mustChange); err != nil {
+		zapx.ErrorCtx(ctx, "update password failed", zap.Error(err))
+		return err
+	}
+	if !mustChange {
+		recordPasswordHistory(ctx, userModel.ID, hashedPassword)
+	}
+	if _, err = session_service.RevokeAll(ctx, userModel.ID); err != nil {
+		// 会话核对时也会因密码摘要变化而失效，这里只记录日志
+		zapx.ErrorCtx(ctx, "revoke sessions after password change failed", zap.Int64("user_id", userModel.ID), zap.Error(err))
+	}
+	return nil
+}
+
+func recordPasswordHistory(ctx context.Context, userID int64, hashedPassword string) {
+	history := &model.AdminPasswordHistory{
+		AdminID:  userID,
+		Password: hashedPassword,
+	}
+	if err := history.Create(ctx, dbs.Admin); err != nil {
+		zapx.ErrorCtx(ctx, "create password history failed", zap.Int64("user_id", userID), zap.Error(err))
+		return
+	}
+	n := conf_service.Int(ctx, confPwdHistory, defaultPwdHistory)
+	if err := history.Prune(ctx, dbs.Admin, userID, max(n, 1)); err != nil {
+		zapx.ErrorCtx(ctx, "prune password history failed", zap.Int64("user_id", userID), zap.Error(err))
+	}
+}
+
+// oneTimePassword 生成包含全部字符类别的随机密码
+func oneTimePassword() (string, error) {
+	sets := []string{
+		"ABCDEFGHJKLMNPQRSTUVWXYZ",
+		"abcdefghijkmnpqrstuvwxyz",
+		"23456789",
+		"!@#$%^&*-_=+",
+	}
+	all := strings.Join(sets, "")
+	b := make([]byte, oneTimePwdLength)
+	for i := range b {
+		set := all
+		if i < len(sets) {
+			set = sets[i]
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = set[n.Int64()]
+	}
+	// 打乱顺序，避免固定位置的字符类别
+	for i := len(b) - 1; i > 0; i-- {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		j := n.Int64()
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,169 @@
+package admin_service
+
+import (
+	"admin/internal/model"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/utils/bcryptx"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	recoveryCodeCount   = 10
+	recoveryCodeLength  = 10 // 不含分隔符
+	recoveryCodeCharset = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+// RecoveryCodesResp 恢复码响应
+type RecoveryCodesResp struct {
+	RecoveryCodes []string `json:"recovery_codes"` // 一次性恢复码，仅返回这一次
+}
+
+// normalizeRecoveryCode 忽略大小写、空格和分隔符
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+func hashRecoveryCode(userID int64, code string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s", userID, normalizeRecoveryCode(code))))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRecoveryCodes 生成一组恢复码，返回明文和待保存的哈希记录
+func generateRecoveryCodes(userID int64) ([]string, []*model.AdminRecoveryCode, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	list := make([]*model.AdminRecoveryCode, 0, recoveryCodeCount)
+	seen := make(map[string]struct{}, recoveryCodeCount)
+	for len(codes) < recoveryCodeCount {
+		b := make([]byte, recoveryCodeLength)
+		for i := range b {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeCharset))))
+			if err != nil {
+				return nil, nil, err
+			}
+			b[i] = recoveryCodeCharset[n.Int64()]
+		}
+		code := fmt.Sprintf("%s-%s", b[:recoveryCodeLength/2], b[recoveryCodeLength/2:])
+		hash := hashRecoveryCode(userID, code)
+		if _, ok := seen[hash]; ok {
+			continue
+		}
+		seen[hash] = struct{}{}
+		codes = append(codes, code)
+		list = append(list, &model.AdminRecoveryCode{AdminID: userID, CodeHash: hash})
+	}
+	return codes, list, nil
+}
+
+// resetRecoveryCodes 生成新的恢复码并使原有恢复码全部失效
+func resetRecoveryCodes(ctx context.Context, userID int64) ([]string, error) {
+	codes, list, err := generateRecoveryCodes(userID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "generate recovery codes failed", zap.Error(err))
+		return nil, err
+	}
+	if err = new(model.AdminRecoveryCode).Replace(ctx, dbs.Admin, userID, list); err != nil {
+		zapx.ErrorCtx(ctx, "save recovery codes failed", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return codes, nil
+}
+
+// useRecoveryCode 校验并消耗一个恢复码，返回剩余可用数量
+func useRecoveryCode(ctx context.Context, userModel *model.Admin, code, ip string) (bool, int64, error) {
+	rc := new(model.AdminRecoveryCode)
+	if err := rc.GetUnused(ctx, dbs.Admin, userModel.ID, hashRecoveryCode(userModel.ID, code)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, 0, nil
+		}
+		zapx.ErrorCtx(ctx, "get recovery code failed", zap.Error(err))
+		return false, 0, err
+	}
+	ok, err := rc.MarkUsed(ctx, dbs.Admin, rc.ID, ip)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "mark recovery code used failed", zap.Error(err))
+		return false, 0, err
+	}
+	if !ok {
+		return false, 0, nil
+	}
+	left, err := rc.CountUnused(ctx, dbs.Admin, userModel.ID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "count recovery codes failed", zap.Error(err))
+	}
+	// 恢复码只在丢失验证器时使用，每次使用都需要告警
+	zapx.WarnCtx(ctx, "mfa recovery code used",
+		zap.Int64("user_id", userModel.ID),
+		zap.String("account", userModel.Account),
+		zap.String("ip", ip),
+		zap.Int64("remaining", left))
+	return true, left, nil
+}
+
+// RegenerateRecoveryCodesReq 重新生成恢复码请求，二次验证使用谷歌验证码或安全密钥，不接受恢复码
+type RegenerateRecoveryCodesReq struct {
+	Password string          `json:"password" binding:"required"`
+	TotpCode string          `json:"totp_code"` // 已绑定谷歌验证器时使用
+	WebAuthn json.RawMessage `json:"webauthn"`  // 已注册安全密钥时使用
+}
+
+// RegenerateRecoveryCodes 重新生成自己的恢复码，原有恢复码全部失效
+func RegenerateRecoveryCodes(ctx context.Context, userID int64, ip string, req *RegenerateRecoveryCodesReq) (*RecoveryCodesResp, error) {
+	userModel := new(model.Admin)
+	if err := userModel.GetByID(ctx, dbs.Admin, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		zapx.ErrorCtx(ctx, "get user by id failed", zap.Error(err))
+		return nil, err
+	}
+	if err := checkLoginGuard(ctx, userModel.Account, ip); err != nil {
+		return nil, err
+	}
+	if !bcryptx.Check(userModel.Password, req.Password) {
+		return nil, loginFailed(ctx, userModel.Account, ip, "密码错误")
+	}
+	mfaReq := &LoginReq{
+		Account:  userModel.Account,
+		TotpCode: req.TotpCode,
+		WebAuthn: req.WebAuthn,
+	}
+	factor, _, err := verifyMFA(ctx, userModel, mfaReq, ip)
+	if err != nil {
+		// 恢复码不能用来重新生成恢复码，不提示该方式
+		var mfaErr *MFARequiredError
+		if errors.As(err, &mfaErr) {
+			mfaErr.Factors = slices.DeleteFunc(mfaErr.Factors, func(f string) bool { return f == mfaFactorRecovery })
+		}
+		return nil, err
+	}
+	if factor == "" {
+		return nil, errors.New("未绑定谷歌验证器或安全密钥")
+	}
+	loginSucceeded(ctx, userModel.Account)
+
+	codes, err := resetRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	zapx.InfoCtx(ctx, "regenerate recovery codes success", zap.Int64("user_id", userID), zap.String("account", userModel.Account))
+	return &RecoveryCodesResp{RecoveryCodes: codes}, nil
+}
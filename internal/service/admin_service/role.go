@@ -0,0 +1,215 @@
+package admin_service
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/model"
+	"admin/internal/service/audit_service"
+	"admin/internal/service/ip_service"
+	"admin/internal/service/perm_service"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const maxRoleNameLength = 40
+
+// 审计日志操作类型
+const (
+	actionRoleCreate = "role.create"
+	actionRoleRename = "role.rename"
+	actionRoleDelete = "role.delete"
+)
+
+// RoleReq 新增/修改角色请求
+type RoleReq struct {
+	ID   int    `json:"id"` // 修改时必填
+	Name string `json:"name" binding:"required"`
+}
+
+// checkRoleName 校验角色名称，名称不能重复
+func checkRoleName(ctx context.Context, name string, exceptID int) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxRoleNameLength {
+		return "", fmt.Errorf("角色名称不能为空且不能超过%d个字符", maxRoleNameLength)
+	}
+	r := new(model.Role)
+	err := r.GetByName(ctx, dbs.Admin, name)
+	if err == nil && r.ID != exceptID {
+		return "", errors.New("角色名称已存在")
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		zapx.ErrorCtx(ctx, "get role by name failed", zap.Error(err))
+		return "", err
+	}
+	return name, nil
+}
+
+// CreateRole 新增角色，新角色没有任何权限，需要另行设置权限模板
+func CreateRole(ctx context.Context, op *audit_service.Operator, req *RoleReq) (*model.Role, error) {
+	name, err := checkRoleName(ctx, req.Name, 0)
+	if err != nil {
+		return nil, err
+	}
+	r := &model.Role{Name: name}
+	if err = r.Create(ctx, dbs.Admin); err != nil {
+		zapx.ErrorCtx(ctx, "create role failed", zap.Error(err))
+		return nil, err
+	}
+	audit_service.Record(ctx, op, actionRoleCreate, strconv.Itoa(r.ID), r)
+	return r, nil
+}
+
+// RenameRole 修改角色名称，超级管理员角色不能修改
+func RenameRole(ctx context.Context, op *audit_service.Operator, req *RoleReq) error {
+	if req.ID == auth.SuperAdminRoleID {
+		return errors.New("超级管理员角色不能修改")
+	}
+	r := new(model.Role)
+	if err := r.GetByID(ctx, dbs.Admin, req.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("角色不存在")
+		}
+		zapx.ErrorCtx(ctx, "get role failed", zap.Error(err))
+		return err
+	}
+	name, err := checkRoleName(ctx, req.Name, req.ID)
+	if err != nil {
+		return err
+	}
+	if name == r.Name {
+		return nil
+	}
+	if err = r.Rename(ctx, dbs.Admin, req.ID, name); err != nil {
+		zapx.ErrorCtx(ctx, "rename role failed", zap.Error(err))
+		return err
+	}
+	audit_service.Record(ctx, op, actionRoleRename, strconv.Itoa(req.ID), map[string]any{
+		"before": r.Name,
+		"after":  name,
+	})
+	return nil
+}
+
+// checkRoleGrant 操作者能否把角色授予他人：非超级管理员不能授予超级管理员角色，
+// 且角色权限模板中的每一项都须在自己可以转授的范围内
+func checkRoleGrant(ctx context.Context, operatorID int64, operatorRoleID, roleID int) error {
+	if operatorRoleID == auth.SuperAdminRoleID {
+		return nil
+	}
+	if roleID == auth.SuperAdminRoleID {
+		return errors.New("仅超级管理员可以授予超级管理员角色")
+	}
+	set, err := perm_service.UserPerms(ctx, operatorID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get operator perms failed", zap.Int64("operator_id", operatorID), zap.Error(err))
+		return err
+	}
+	perms, err := perm_service.RolePerms(ctx, roleID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get role perms failed", zap.Int("role_id", roleID), zap.Error(err))
+		return err
+	}
+	for _, perm := range perms {
+		if !set.CanGrant(perm) {
+			return fmt.Errorf("角色权限超出了可以授予的范围: %s", perm)
+		}
+	}
+	return nil
+}
+
+// DeleteRoleReq 删除角色请求
+type DeleteRoleReq struct {
+	ID         int `json:"id" binding:"required"`
+	ReassignTo int `json:"reassign_to"` // 角色下还有管理员时必填，管理员将转移到该角色
+}
+
+// DeleteRole 删除角色及其权限模板，角色下还有管理员时必须先转移，超级管理员角色不能删除
+func DeleteRole(ctx context.Context, op *audit_service.Operator, req *DeleteRoleReq) error {
+	if req.ID == auth.SuperAdminRoleID {
+		return errors.New("超级管理员角色不能删除")
+	}
+	r := new(model.Role)
+	if err := r.GetByID(ctx, dbs.Admin, req.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("角色不存在")
+		}
+		zapx.ErrorCtx(ctx, "get role failed", zap.Error(err))
+		return err
+	}
+	if req.ReassignTo > 0 {
+		if req.ReassignTo == req.ID {
+			return errors.New("不能转移到要删除的角色")
+		}
+		// 批量转移不能用来授予超级管理员角色
+		if req.ReassignTo == auth.SuperAdminRoleID {
+			return errors.New("不能把管理员批量转移到超级管理员角色")
+		}
+		exists, err := r.Exists(ctx, dbs.Admin, req.ReassignTo)
+		if err != nil {
+			zapx.ErrorCtx(ctx, "check role exists failed", zap.Error(err))
+			return err
+		}
+		if !exists {
+			return errors.New("要转移到的角色不存在")
+		}
+		if err = checkRoleGrant(ctx, op.ID, op.RoleID, req.ReassignTo); err != nil {
+			return err
+		}
+	}
+
+	// 在事务中锁定角色下的管理员再统计，避免并发修改角色后留下指向已删除角色的管理员
+	var (
+		adminIDs []int64
+		errInUse = errors.New("role in use")
+	)
+	err := dbs.Admin.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		admin := new(model.Admin)
+		ids, err := admin.LockIDsByRole(ctx, tx, req.ID)
+		if err != nil {
+			return err
+		}
+		adminIDs = ids
+		if req.ReassignTo > 0 {
+			err = admin.ReassignRole(ctx, tx, req.ID, req.ReassignTo)
+		} else if len(ids) > 0 {
+			return errInUse
+		} else {
+			err = admin.DetachDeletedRole(ctx, tx, req.ID)
+		}
+		if err != nil {
+			return err
+		}
+		if err = new(model.AdminIPRule).DeleteByTarget(ctx, tx, model.IPRuleLevelRole, int64(req.ID)); err != nil {
+			return err
+		}
+		if err = new(model.RolePerm).DeleteByRoleID(ctx, tx, req.ID); err != nil {
+			return err
+		}
+		return r.Delete(ctx, tx, req.ID)
+	})
+	if errors.Is(err, errInUse) {
+		return fmt.Errorf("角色下还有%d个管理员，请指定要转移到的角色", len(adminIDs))
+	}
+	if err != nil {
+		zapx.ErrorCtx(ctx, "delete role failed", zap.Int("role_id", req.ID), zap.Error(err))
+		return err
+	}
+	ip_service.InvalidateRules()
+	for _, id := range adminIDs {
+		afterAdminChanged(ctx, id)
+	}
+
+	audit_service.Record(ctx, op, actionRoleDelete, strconv.Itoa(req.ID), map[string]any{
+		"role":        r,
+		"reassign_to": req.ReassignTo,
+		"admins":      adminIDs,
+	})
+	return nil
+}
@@ -0,0 +1,74 @@
+package admin_service
+
+import (
+	"admin/internal/model"
+	"admin/internal/service/conf_service"
+	"admin/internal/service/session_service"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+	"wallet/common-lib/config"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/utils/bcryptx"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	confStepUpMinutes    = "stepup_minutes" // 重新验证身份后敏感操作的有效时长(分钟)
+	defaultStepUpMinutes = 5
+)
+
+// StepUpReq 重新验证身份请求
+type StepUpReq struct {
+	Password string          `json:"password" binding:"required"`
+	TotpCode string          `json:"totp_code"` // 已绑定谷歌验证器时使用
+	WebAuthn json.RawMessage `json:"webauthn"`  // 已注册安全密钥时使用
+}
+
+// StepUpResp 重新验证身份响应
+type StepUpResp struct {
+	ElevatedUntil int64 `json:"elevated_until"` // 敏感操作的有效截止时间戳
+}
+
+// StepUp 当前会话重新验证密码和二次验证，通过后短时间内可以执行敏感操作
+// 失败计入登录失败次数，防止被盗用的会话暴力尝试
+func StepUp(ctx context.Context, userID int64, sid, ip string, req *StepUpReq, svrConf *config.ServiceConfig) (*StepUpResp, error) {
+	userModel := new(model.Admin)
+	if err := userModel.GetByID(ctx, dbs.Admin, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		zapx.ErrorCtx(ctx, "get user by id failed", zap.Error(err))
+		return nil, err
+	}
+	if err := checkLoginGuard(ctx, userModel.Account, ip); err != nil {
+		return nil, err
+	}
+	if !bcryptx.Check(userModel.Password, req.Password) {
+		return nil, loginFailed(ctx, userModel.Account, ip, "密码错误")
+	}
+	if svrConf.Service.Auth.Login.Totp {
+		mfaReq := &LoginReq{
+			Account:  userModel.Account,
+			TotpCode: req.TotpCode,
+			WebAuthn: req.WebAuthn,
+		}
+		if _, _, err := verifyMFA(ctx, userModel, mfaReq, ip); err != nil {
+			return nil, err
+		}
+	}
+	loginSucceeded(ctx, userModel.Account)
+
+	minutes := max(conf_service.Int(ctx, confStepUpMinutes, defaultStepUpMinutes), 1)
+	until := time.Now().Add(time.Duration(minutes) * time.Minute)
+	if err := session_service.Elevate(ctx, sid, until); err != nil {
+		return nil, err
+	}
+
+	zapx.InfoCtx(ctx, "step-up success", zap.Int64("user_id", userID), zap.String("account", userModel.Account), zap.String("ip", ip))
+	return &StepUpResp{ElevatedUntil: until.Unix()}, nil
+}
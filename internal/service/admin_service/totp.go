@@ -0,0 +1,81 @@
+package admin_service
+
+import (
+	"admin/internal/service/conf_service"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+	"time"
+	"wallet/common-lib/rdb"
+	"wallet/common-lib/zapx"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	confTotpSkew    = "totp_skew" // 允许的时钟偏差（前后各几个周期），生产环境可设为0
+	defaultTotpSkew = 1
+	maxTotpSkew     = 3
+	totpPeriod      = 30
+
+	totpStepPrefix = "admin.totp.step:" // 每个管理员最近一次通过校验的时间步
+)
+
+// 只有时间步大于上次通过的时间步才允许使用，防止同一动态码在有效期内被重放
+var totpStepScript = redis.NewScript(`
+local last = tonumber(redis.call('GET', KEYS[1]) or '-1')
+if tonumber(ARGV[1]) <= last then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[2])
+return 1
+`)
+
+// matchTOTP 在允许的时钟偏差内查找与动态码匹配的时间步
+func matchTOTP(secret, code string, skew int, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	counter := now.Unix() / totpPeriod
+	for i := -skew; i <= skew; i++ {
+		step := counter + int64(i)
+		if step < 0 {
+			continue
+		}
+		expected, err := hotp.GenerateCodeCustom(secret, uint64(step), hotp.ValidateOpts{
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// useTOTP 校验动态码并记录其时间步，同一管理员不能重复使用已通过的动态码
+func useTOTP(ctx context.Context, userID int64, secret, code string) (bool, error) {
+	skew := min(max(conf_service.Int(ctx, confTotpSkew, defaultTotpSkew), 0), maxTotpSkew)
+	step, ok := matchTOTP(secret, code, skew, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	key := fmt.Sprintf("%s%d", totpStepPrefix, userID)
+	ttl := (2*skew + 2) * totpPeriod
+	res, err := totpStepScript.Run(ctx, rdb.Client, []string{key}, step, ttl).Int()
+	if err != nil {
+		zapx.ErrorCtx(ctx, "record totp step failed", zap.Int64("user_id", userID), zap.Error(err))
+		return false, err
+	}
+	if res == 0 {
+		zapx.WarnCtx(ctx, "totp code replayed", zap.Int64("user_id", userID), zap.Int64("step", step))
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,455 @@
+package admin_service
+
+import (
+	"admin/internal/model"
+	"admin/internal/service/conf_service"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/rdb"
+	"wallet/common-lib/utils/bcryptx"
+	"wallet/common-lib/zapx"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// WebAuthn配置
+const (
+	confWebauthnRPID    = "webauthn_rp_id"   // 依赖方ID，一般为后台域名，为空表示未启用
+	confWebauthnRPName  = "webauthn_rp_name" // 依赖方显示名称
+	confWebauthnOrigins = "webauthn_origins" // 允许的来源，逗号分隔，如 https://admin.example.com
+
+	defaultWebauthnRPName = "admin"
+)
+
+const (
+	webauthnCeremonyTTL    = 5 * time.Minute
+	maxWebauthnCredentials = 10
+	maxWebauthnNameLength  = 40
+
+	webauthnRegPrefix   = "admin.webauthn.reg:"   // 注册流程状态
+	webauthnLoginPrefix = "admin.webauthn.login:" // 登录流程状态
+)
+
+var ErrWebauthnDisabled = errors.New("未启用WebAuthn")
+
+// newWebAuthn 按系统配置创建WebAuthn实例
+func newWebAuthn(ctx context.Context) (*webauthn.WebAuthn, error) {
+	return buildWebAuthn(
+		conf_service.String(ctx, confWebauthnRPID, ""),
+		conf_service.String(ctx, confWebauthnRPName, defaultWebauthnRPName),
+		conf_service.String(ctx, confWebauthnOrigins, ""))
+}
+
+// buildWebAuthn 创建WebAuthn实例，origins为逗号分隔的来源，为空时使用 https://rpID
+func buildWebAuthn(rpID, rpName, originList string) (*webauthn.WebAuthn, error) {
+	if rpID == "" {
+		return nil, ErrWebauthnDisabled
+	}
+	var origins []string
+	for _, o := range strings.Split(originList, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	if len(origins) == 0 {
+		origins = []string{"https://" + rpID}
+	}
+	return webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: rpName,
+		RPOrigins:     origins,
+	})
+}
+
+// webauthnUser 实现webauthn.User
+type webauthnUser struct {
+	admin   *model.Admin
+	records []*model.AdminWebauthnCredential
+	creds   []webauthn.Credential
+}
+
+func (u *webauthnUser) WebAuthnID() []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(u.admin.ID))
+}
+
+func (u *webauthnUser) WebAuthnName() string {
+	return u.admin.Account
+}
+
+func (u *webauthnUser) WebAuthnDisplayName() string {
+	return u.admin.Account
+}
+
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.creds
+}
+
+func (u *webauthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+// record 根据凭证ID查找对应的记录
+func (u *webauthnUser) record(id []byte) *model.AdminWebauthnCredential {
+	for _, r := range u.records {
+		if bytes.Equal(r.CredentialID, id) {
+			return r
+		}
+	}
+	return nil
+}
+
+func loadWebauthnUser(ctx context.Context, userModel *model.Admin) (*webauthnUser, error) {
+	records, err := new(model.AdminWebauthnCredential).GetByAdmin(ctx, dbs.Admin, userModel.ID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get webauthn credentials failed", zap.Int64("user_id", userModel.ID), zap.Error(err))
+		return nil, err
+	}
+	u := &webauthnUser{
+		admin:   userModel,
+		records: records,
+		creds:   make([]webauthn.Credential, 0, len(records)),
+	}
+	for _, r := range records {
+		var c webauthn.Credential
+		if err = json.Unmarshal(r.Credential, &c); err != nil {
+			zapx.ErrorCtx(ctx, "decode webauthn credential failed", zap.Int64("id", r.ID), zap.Error(err))
+			continue
+		}
+		u.creds = append(u.creds, c)
+	}
+	return u, nil
+}
+
+func webauthnKey(prefix string, userID int64) string {
+	return fmt.Sprintf("%s%d", prefix, userID)
+}
+
+// saveCeremony 保存流程状态，每个管理员同时只有一个进行中的注册/登录流程
+func saveCeremony(ctx context.Context, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return rdb.Client.Set(ctx, key, data, webauthnCeremonyTTL).Err()
+}
+
+// takeCeremony 取出并删除流程状态，保证每个挑战只能使用一次
+func takeCeremony(ctx context.Context, key string, v any) (bool, error) {
+	data, err := rdb.Client.GetDel(ctx, key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, json.Unmarshal(data, v)
+}
+
+// WebauthnRegisterBeginReq 开始注册安全密钥请求
+type WebauthnRegisterBeginReq struct {
+	Password string `json:"password" binding:"required"`
+	Name     string `json:"name" binding:"required"` // 设备名称
+}
+
+type webauthnRegState struct {
+	Name    string               `json:"name"`
+	Session webauthn.SessionData `json:"session"`
+}
+
+// BeginWebauthnRegister 开始注册安全密钥，返回浏览器navigator.credentials.create所需参数
+func BeginWebauthnRegister(ctx context.Context, userID int64, req *WebauthnRegisterBeginReq) (*protocol.CredentialCreation, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len([]rune(req.Name)) > maxWebauthnNameLength {
+		return nil, fmt.Errorf("设备名称长度需在1-%d之间", maxWebauthnNameLength)
+	}
+	wa, err := newWebAuthn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userModel := new(model.Admin)
+	if err = userModel.GetByID(ctx, dbs.Admin, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		zapx.ErrorCtx(ctx, "get user by id failed", zap.Error(err))
+		return nil, err
+	}
+	if !bcryptx.Check(userModel.Password, req.Password) {
+		return nil, errors.New("密码错误")
+	}
+
+	user, err := loadWebauthnUser(ctx, userModel)
+	if err != nil {
+		return nil, err
+	}
+	if len(user.records) >= maxWebauthnCredentials {
+		return nil, fmt.Errorf("最多只能注册%d个安全密钥", maxWebauthnCredentials)
+	}
+	creation, session, err := wa.BeginRegistration(user, registrationOptions(user)...)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "begin webauthn registration failed", zap.Error(err))
+		return nil, err
+	}
+	state := &webauthnRegState{Name: req.Name, Session: *session}
+	if err = saveCeremony(ctx, webauthnKey(webauthnRegPrefix, userID), state); err != nil {
+		zapx.ErrorCtx(ctx, "save webauthn registration state failed", zap.Error(err))
+		return nil, err
+	}
+	return creation, nil
+}
+
+// registrationOptions 注册选项: 排除已注册的安全密钥，避免同一设备重复注册
+func registrationOptions(user *webauthnUser) []webauthn.RegistrationOption {
+	exclusions := make([]protocol.CredentialDescriptor, 0, len(user.creds))
+	for _, c := range user.creds {
+		exclusions = append(exclusions, c.Descriptor())
+	}
+	return []webauthn.RegistrationOption{
+		webauthn.WithExclusions(exclusions),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			UserVerification: protocol.VerificationPreferred,
+		}),
+	}
+}
+
+// WebauthnRegisterFinishReq 完成注册安全密钥请求
+type WebauthnRegisterFinishReq struct {
+	Credential json.RawMessage `json:"credential" binding:"required"` // navigator.credentials.create的返回值
+}
+
+// WebauthnRegisterFinishResp 完成注册安全密钥响应
+type WebauthnRegisterFinishResp struct {
+	ID            int64    `json:"id"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"` // 首次绑定二次验证时返回恢复码，仅返回这一次
+}
+
+// FinishWebauthnRegister 校验并保存安全密钥
+func FinishWebauthnRegister(ctx context.Context, userID int64, req *WebauthnRegisterFinishReq) (*WebauthnRegisterFinishResp, error) {
+	wa, err := newWebAuthn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	state := new(webauthnRegState)
+	ok, err := takeCeremony(ctx, webauthnKey(webauthnRegPrefix, userID), state)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get webauthn registration state failed", zap.Error(err))
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("注册已过期，请重新开始")
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(req.Credential))
+	if err != nil {
+		return nil, errors.New("安全密钥返回数据格式错误")
+	}
+
+	userModel := new(model.Admin)
+	if err = userModel.GetByID(ctx, dbs.Admin, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		zapx.ErrorCtx(ctx, "get user by id failed", zap.Error(err))
+		return nil, err
+	}
+	user, err := loadWebauthnUser(ctx, userModel)
+	if err != nil {
+		return nil, err
+	}
+	cred, err := wa.CreateCredential(user, state.Session, parsed)
+	if err != nil {
+		zapx.WarnCtx(ctx, "webauthn registration rejected", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, errors.New("安全密钥校验失败")
+	}
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return nil, err
+	}
+
+	firstFactor := len(userModel.MfaSecret) == 0 && len(user.records) == 0
+	record := &model.AdminWebauthnCredential{
+		AdminID:      userID,
+		Name:         state.Name,
+		CredentialID: cred.ID,
+		Credential:   data,
+	}
+	if err = record.Create(ctx, dbs.Admin); err != nil {
+		zapx.ErrorCtx(ctx, "save webauthn credential failed", zap.Error(err))
+		return nil, err
+	}
+
+	resp := &WebauthnRegisterFinishResp{ID: record.ID}
+	if firstFactor {
+		if resp.RecoveryCodes, err = resetRecoveryCodes(ctx, userID); err != nil {
+			// 安全密钥已保存，恢复码可稍后重新生成
+			zapx.ErrorCtx(ctx, "generate recovery codes after webauthn registration failed", zap.Error(err))
+		}
+	}
+	afterMFAEnrolled(ctx, userModel)
+
+	zapx.InfoCtx(ctx, "webauthn credential registered",
+		zap.Int64("user_id", userID),
+		zap.String("account", userModel.Account),
+		zap.Int64("credential", record.ID),
+		zap.String("name", record.Name))
+	return resp, nil
+}
+
+// beginWebauthnLogin 为密码校验通过的管理员发起WebAuthn登录挑战
+func beginWebauthnLogin(ctx context.Context, user *webauthnUser) (*protocol.CredentialAssertion, error) {
+	wa, err := newWebAuthn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	assertion, session, err := wa.BeginLogin(user)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "begin webauthn login failed", zap.Error(err))
+		return nil, err
+	}
+	if err = saveCeremony(ctx, webauthnKey(webauthnLoginPrefix, user.admin.ID), session); err != nil {
+		zapx.ErrorCtx(ctx, "save webauthn login state failed", zap.Error(err))
+		return nil, err
+	}
+	return assertion, nil
+}
+
+// finishWebauthnLogin 校验WebAuthn登录断言
+func finishWebauthnLogin(ctx context.Context, user *webauthnUser, raw json.RawMessage, ip string) (bool, error) {
+	wa, err := newWebAuthn(ctx)
+	if err != nil {
+		return false, err
+	}
+	session := new(webauthn.SessionData)
+	ok, err := takeCeremony(ctx, webauthnKey(webauthnLoginPrefix, user.admin.ID), session)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get webauthn login state failed", zap.Error(err))
+		return false, err
+	}
+	if !ok {
+		return false, nil
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(raw))
+	if err != nil {
+		return false, nil
+	}
+	cred, err := wa.ValidateLogin(user, *session, parsed)
+	if err != nil {
+		zapx.WarnCtx(ctx, "webauthn assertion rejected", zap.Int64("user_id", user.admin.ID), zap.Error(err))
+		return false, nil
+	}
+	record := user.record(cred.ID)
+	if record == nil {
+		return false, nil
+	}
+	// 签名计数回退说明凭证可能被复制
+	if cred.Authenticator.CloneWarning {
+		zapx.WarnCtx(ctx, "webauthn clone warning",
+			zap.Int64("user_id", user.admin.ID),
+			zap.Int64("credential", record.ID),
+			zap.String("ip", ip))
+		return false, nil
+	}
+
+	data, err := json.Marshal(cred)
+	if err == nil {
+		err = record.UpdateUsage(ctx, dbs.Admin, record.ID, data, ip)
+	}
+	if err != nil {
+		// 不影响登录流程，只记录日志
+		zapx.ErrorCtx(ctx, "update webauthn credential usage failed", zap.Int64("credential", record.ID), zap.Error(err))
+	}
+	return true, nil
+}
+
+// WebauthnCredentialItem 安全密钥信息
+type WebauthnCredentialItem struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func toWebauthnItems(records []*model.AdminWebauthnCredential) []*WebauthnCredentialItem {
+	items := make([]*WebauthnCredentialItem, 0, len(records))
+	for _, r := range records {
+		items = append(items, &WebauthnCredentialItem{
+			ID:         r.ID,
+			Name:       r.Name,
+			LastUsedAt: r.LastUsedAt,
+			LastUsedIP: r.LastUsedIP,
+			CreatedAt:  r.CreatedAt,
+		})
+	}
+	return items
+}
+
+// ListWebauthnCredentials 列出管理员的安全密钥
+func ListWebauthnCredentials(ctx context.Context, userID int64) ([]*WebauthnCredentialItem, error) {
+	records, err := new(model.AdminWebauthnCredential).GetByAdmin(ctx, dbs.Admin, userID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get webauthn credentials failed", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return toWebauthnItems(records), nil
+}
+
+// RevokeWebauthnReq 删除安全密钥请求
+type RevokeWebauthnReq struct {
+	ID       int64  `json:"id" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// RevokeWebauthnCredential 删除自己的安全密钥；角色要求二次验证时不能删除最后一个二次验证方式
+func RevokeWebauthnCredential(ctx context.Context, userID int64, req *RevokeWebauthnReq) error {
+	userModel := new(model.Admin)
+	if err := userModel.GetByID(ctx, dbs.Admin, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("用户不存在")
+		}
+		zapx.ErrorCtx(ctx, "get user by id failed", zap.Error(err))
+		return err
+	}
+	if !bcryptx.Check(userModel.Password, req.Password) {
+		return errors.New("密码错误")
+	}
+	records, err := new(model.AdminWebauthnCredential).GetByAdmin(ctx, dbs.Admin, userID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get webauthn credentials failed", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	lastFactor := len(userModel.MfaSecret) == 0 && len(records) == 1 && records[0].ID == req.ID
+	if lastFactor && mfaRequired(ctx, userModel.RoleID) {
+		return errors.New("角色要求二次验证，请先绑定谷歌验证器或其他安全密钥后再删除")
+	}
+	ok, err := new(model.AdminWebauthnCredential).Delete(ctx, dbs.Admin, userID, req.ID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "delete webauthn credential failed", zap.Error(err))
+		return err
+	}
+	if !ok {
+		return errors.New("安全密钥不存在")
+	}
+	if lastFactor {
+		// 已没有二次验证方式，恢复码不再使用
+		if err = new(model.AdminRecoveryCode).DeleteByAdmin(ctx, dbs.Admin, userID); err != nil {
+			zapx.ErrorCtx(ctx, "delete recovery codes failed", zap.Int64("user_id", userID), zap.Error(err))
+		}
+	}
+	zapx.InfoCtx(ctx, "webauthn credential revoked",
+		zap.Int64("user_id", userID),
+		zap.String("account", userModel.Account),
+		zap.Int64("credential", req.ID))
+	return nil
+}
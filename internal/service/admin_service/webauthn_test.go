@@ -0,0 +1,290 @@
+package admin_service
+
+import (
+	"admin/internal/model"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const (
+	testRPID   = "admin.example.com"
+	testOrigin = "https://admin.example.com"
+)
+
+// softAuthenticator 软件实现的安全密钥，使用P-256密钥和none格式的证明
+type softAuthenticator struct {
+	key     *ecdsa.PrivateKey
+	credID  []byte
+	counter uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credID := make([]byte, 16)
+	if _, err = rand.Read(credID); err != nil {
+		t.Fatal(err)
+	}
+	return &softAuthenticator{key: key, credID: credID}
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// authData rpIdHash | flags | signCount [| attestedCredentialData]
+func (a *softAuthenticator) authData(t *testing.T, attested bool) []byte {
+	t.Helper()
+	rpHash := sha256.Sum256([]byte(testRPID))
+	flags := byte(protocol.FlagUserPresent | protocol.FlagUserVerified)
+	if attested {
+		flags |= byte(protocol.FlagAttestedCredentialData)
+	}
+	data := append(rpHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.counter)
+	if !attested {
+		return data
+	}
+	pub, err := webauthncbor.Marshal(map[int]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: a.key.X.FillBytes(make([]byte, 32)),
+		-3: a.key.Y.FillBytes(make([]byte, 32)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, make([]byte, 16)...) // aaguid
+	data = binary.BigEndian.AppendUint16(data, uint16(len(a.credID)))
+	data = append(data, a.credID...)
+	return append(data, pub...)
+}
+
+func clientData(t *testing.T, typ string, challenge protocol.URLEncodedBase64, origin string) []byte {
+	t.Helper()
+	data, err := json.Marshal(map[string]string{
+		"type":      typ,
+		"challenge": challenge.String(),
+		"origin":    origin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// create 生成注册响应
+func (a *softAuthenticator) create(t *testing.T, creation *protocol.CredentialCreation, origin string) []byte {
+	t.Helper()
+	att, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": a.authData(t, true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(map[string]any{
+		"id":    b64(a.credID),
+		"rawId": b64(a.credID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64(clientData(t, "webauthn.create", creation.Response.Challenge, origin)),
+			"attestationObject": b64(att),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// get 生成登录断言，签名计数器先加一
+func (a *softAuthenticator) get(t *testing.T, assertion *protocol.CredentialAssertion, userHandle []byte, origin string) []byte {
+	t.Helper()
+	a.counter++
+	authData := a.authData(t, false)
+	cd := clientData(t, "webauthn.get", assertion.Response.Challenge, origin)
+	cdHash := sha256.Sum256(cd)
+	digest := sha256.Sum256(append(bytes.Clone(authData), cdHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(map[string]any{
+		"id":    b64(a.credID),
+		"rawId": b64(a.credID),
+		"type":  "public-key",
+		"response": map[string]string{
+			"clientDataJSON":    b64(cd),
+			"authenticatorData": b64(authData),
+			"signature":         b64(sig),
+			"userHandle":        b64(userHandle),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func newTestWebAuthn(t *testing.T) *webauthn.WebAuthn {
+	t.Helper()
+	wa, err := buildWebAuthn(testRPID, defaultWebauthnRPName, testOrigin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wa
+}
+
+// registerKey 完成注册，返回按数据库存储格式往返后的凭证
+func registerKey(t *testing.T, wa *webauthn.WebAuthn, user *webauthnUser, a *softAuthenticator) webauthn.Credential {
+	t.Helper()
+	creation, session, err := wa.BeginRegistration(user, registrationOptions(user)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(a.create(t, creation, testOrigin)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := wa.CreateCredential(user, *session, parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored webauthn.Credential
+	if err = json.Unmarshal(data, &stored); err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+// assertLogin 完成一次登录，返回更新后的凭证
+func assertLogin(wa *webauthn.WebAuthn, user *webauthnUser, body func(*protocol.CredentialAssertion) []byte) (*webauthn.Credential, error) {
+	assertion, session, err := wa.BeginLogin(user)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(body(assertion)))
+	if err != nil {
+		return nil, err
+	}
+	return wa.ValidateLogin(user, *session, parsed)
+}
+
+func TestWebauthnRegisterAndLogin(t *testing.T) {
+	wa := newTestWebAuthn(t)
+	user := &webauthnUser{admin: &model.Admin{ID: 42, Account: "alice"}}
+	a := newSoftAuthenticator(t)
+
+	stored := registerKey(t, wa, user, a)
+	if !bytes.Equal(stored.ID, a.credID) {
+		t.Fatalf("credential id = %x, want %x", stored.ID, a.credID)
+	}
+	user.creds = []webauthn.Credential{stored}
+
+	// 已注册的密钥不能再次注册
+	creation, _, err := wa.BeginRegistration(user, registrationOptions(user)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(creation.Response.CredentialExcludeList); n != 1 {
+		t.Fatalf("exclude list has %d entries, want 1", n)
+	}
+
+	cred, err := assertLogin(wa, user, func(as *protocol.CredentialAssertion) []byte {
+		return a.get(t, as, user.WebAuthnID(), testOrigin)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Authenticator.CloneWarning {
+		t.Fatal("unexpected clone warning")
+	}
+	if cred.Authenticator.SignCount != a.counter {
+		t.Fatalf("sign count = %d, want %d", cred.Authenticator.SignCount, a.counter)
+	}
+}
+
+func TestWebauthnLoginRejected(t *testing.T) {
+	wa := newTestWebAuthn(t)
+	user := &webauthnUser{admin: &model.Admin{ID: 42, Account: "alice"}}
+	a := newSoftAuthenticator(t)
+	user.creds = []webauthn.Credential{registerKey(t, wa, user, a)}
+
+	cases := []struct {
+		name string
+		body func(*protocol.CredentialAssertion) []byte
+	}{
+		{"wrong origin", func(as *protocol.CredentialAssertion) []byte {
+			return a.get(t, as, user.WebAuthnID(), "https://evil.example.com")
+		}},
+		{"other user", func(as *protocol.CredentialAssertion) []byte {
+			return a.get(t, as, (&webauthnUser{admin: &model.Admin{ID: 43}}).WebAuthnID(), testOrigin)
+		}},
+		{"unknown key", func(as *protocol.CredentialAssertion) []byte {
+			return newSoftAuthenticator(t).get(t, as, user.WebAuthnID(), testOrigin)
+		}},
+		{"tampered signature", func(as *protocol.CredentialAssertion) []byte {
+			other := &softAuthenticator{key: newSoftAuthenticator(t).key, credID: a.credID, counter: a.counter}
+			return other.get(t, as, user.WebAuthnID(), testOrigin)
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := assertLogin(wa, user, tc.body); err == nil {
+				t.Fatal("login should fail")
+			}
+		})
+	}
+}
+
+// 签名计数器回退说明密钥可能被克隆，登录时会拒绝
+func TestWebauthnCloneWarning(t *testing.T) {
+	wa := newTestWebAuthn(t)
+	user := &webauthnUser{admin: &model.Admin{ID: 42, Account: "alice"}}
+	a := newSoftAuthenticator(t)
+	user.creds = []webauthn.Credential{registerKey(t, wa, user, a)}
+
+	a.counter = 9
+	cred, err := assertLogin(wa, user, func(as *protocol.CredentialAssertion) []byte {
+		return a.get(t, as, user.WebAuthnID(), testOrigin)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cred.Authenticator.CloneWarning {
+		t.Fatal("unexpected clone warning")
+	}
+	user.creds = []webauthn.Credential{*cred}
+
+	a.counter = 3
+	cred, err = assertLogin(wa, user, func(as *protocol.CredentialAssertion) []byte {
+		return a.get(t, as, user.WebAuthnID(), testOrigin)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cred.Authenticator.CloneWarning {
+		t.Fatal("counter regression should raise clone warning")
+	}
+}
@@ -0,0 +1,80 @@
+package audit_service
+
+import (
+	"admin/internal/model"
+	"context"
+	"encoding/json"
+	"time"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/dto/req_dto"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+)
+
+// Operator 操作者
+type Operator struct {
+	ID      int64
+	Account string
+	RoleID  int
+	IP      string
+}
+
+// Record 记录一次管理操作，写入失败不影响操作本身
+func Record(ctx context.Context, op *Operator, action, target string, detail any) {
+	data, err := json.Marshal(detail)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "marshal audit detail failed", zap.String("action", action), zap.Error(err))
+		data = []byte("null")
+	}
+	l := &model.AdminAuditLog{
+		OperatorID:      op.ID,
+		OperatorAccount: op.Account,
+		IP:              op.IP,
+		Action:          action,
+		Target:          target,
+		Detail:          data,
+	}
+	if err = l.Create(ctx, dbs.Admin); err != nil {
+		zapx.ErrorCtx(ctx, "create audit log failed",
+			zap.Int64("operator_id", op.ID),
+			zap.String("action", action),
+			zap.String("target", target),
+			zap.ByteString("detail", data),
+			zap.Error(err))
+	}
+}
+
+// ListReq 审计日志列表请求
+type ListReq struct {
+	req_dto.PageArgs
+	OperatorID int64  `json:"operator_id"` // 操作者筛选
+	Action     string `json:"action"`      // 操作类型筛选，前缀匹配，如 allowlist.
+	Target     string `json:"target"`      // 操作对象筛选
+	From       int64  `json:"from"`        // 开始时间（时间戳）
+	To         int64  `json:"to"`          // 结束时间（时间戳）
+}
+
+// List 分页查询审计日志
+func List(ctx context.Context, req *ListReq) ([]*model.AdminAuditLog, int64, error) {
+	req.PageArgs.Init()
+	f := &model.AuditLogFilter{
+		OperatorID: req.OperatorID,
+		Action:     req.Action,
+		Target:     req.Target,
+	}
+	if req.From > 0 {
+		t := time.Unix(req.From, 0)
+		f.From = &t
+	}
+	if req.To > 0 {
+		t := time.Unix(req.To, 0)
+		f.To = &t
+	}
+	list, total, err := new(model.AdminAuditLog).GetList(ctx, dbs.Admin, req.Page, req.Size, f)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "audit.GetList failed", zap.Error(err))
+		return nil, 0, err
+	}
+	return list, total, nil
+}
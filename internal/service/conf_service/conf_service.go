@@ -0,0 +1,92 @@
+package conf_service
+
+import (
+	"admin/internal/model"
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 系统配置会被中间件频繁读取，进程内缓存一小段时间
+const cacheTTL = 30 * time.Second
+
+type entry struct {
+	val   string
+	found bool
+	at    time.Time
+}
+
+var cache sync.Map // key -> *entry
+
+// String 读取字符串配置，未配置时返回def
+func String(ctx context.Context, key, def string) string {
+	val, found := get(ctx, key)
+	if !found {
+		return def
+	}
+	return val
+}
+
+// Int 读取整型配置，未配置或格式错误时返回def
+func Int(ctx context.Context, key string, def int) int {
+	val, found := get(ctx, key)
+	if !found {
+		return def
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		zapx.WarnCtx(ctx, "invalid int system conf", zap.String("key", key), zap.String("val", val))
+		return def
+	}
+	return n
+}
+
+// Bool 读取开关配置，"1"/"true"为开启
+func Bool(ctx context.Context, key string, def bool) bool {
+	val, found := get(ctx, key)
+	if !found {
+		return def
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(val))
+	if err != nil {
+		zapx.WarnCtx(ctx, "invalid bool system conf", zap.String("key", key), zap.String("val", val))
+		return def
+	}
+	return b
+}
+
+// Invalidate 配置修改后清除进程内缓存
+func Invalidate(key string) {
+	cache.Delete(key)
+}
+
+func get(ctx context.Context, key string) (string, bool) {
+	if v, ok := cache.Load(key); ok {
+		e := v.(*entry)
+		if time.Since(e.at) < cacheTTL {
+			return e.val, e.found
+		}
+	}
+	conf := new(model.SystemConf)
+	err := conf.GetByKey(ctx, dbs.System, key)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		zapx.ErrorCtx(ctx, "get system conf failed", zap.String("key", key), zap.Error(err))
+		// 读库失败时沿用旧值
+		if v, ok := cache.Load(key); ok {
+			e := v.(*entry)
+			return e.val, e.found
+		}
+		return "", false
+	}
+	e := &entry{val: conf.Val, found: err == nil && conf.Val != "", at: time.Now()}
+	cache.Store(key, e)
+	return e.val, e.found
+}
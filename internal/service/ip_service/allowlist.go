@@ -0,0 +1,334 @@
+package ip_service
+
+import (
+	"admin/internal/model"
+	"admin/internal/service/audit_service"
+	"admin/internal/service/conf_service"
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 白名单和规则在每次请求时读取，进程内缓存一小段时间，修改后立即失效
+const cacheTTL = 30 * time.Second
+
+const maxLabelLength = 40
+
+// 旧版登录IP白名单（system_conf），逗号分隔的IP或正则表达式，白名单表为空时继续生效
+const confLegacyWhitelist = "ip_whitelist"
+
+// 审计日志操作类型
+const (
+	actionAllowlistCreate = "allowlist.create"
+	actionAllowlistUpdate = "allowlist.update"
+	actionAllowlistDelete = "allowlist.delete"
+)
+
+type cache[T any] struct {
+	sync.Mutex
+	list []*T
+	at   time.Time
+}
+
+func (c *cache[T]) load(fetch func() ([]*T, error)) ([]*T, error) {
+	c.Lock()
+	defer c.Unlock()
+	if c.list != nil && time.Since(c.at) < cacheTTL {
+		return c.list, nil
+	}
+	list, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []*T{}
+	}
+	c.list = list
+	c.at = time.Now()
+	return list, nil
+}
+
+func (c *cache[T]) invalidate() {
+	c.Lock()
+	c.list = nil
+	c.Unlock()
+}
+
+var allowlistCache cache[model.AdminIPAllowlist]
+
+// ParsePrefix 解析CIDR，单个IP视为/32或/128
+func ParsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if p.Addr().Is4In6() {
+		p = netip.PrefixFrom(p.Addr().Unmap(), max(p.Bits()-96, 0))
+	}
+	return p.Masked(), nil
+}
+
+// parseAddr 解析客户端IP，IPv4映射的IPv6地址按IPv4处理
+func parseAddr(ip string) (netip.Addr, bool) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+func matchAny(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func loadAllowlist(ctx context.Context) ([]*model.AdminIPAllowlist, error) {
+	return allowlistCache.load(func() ([]*model.AdminIPAllowlist, error) {
+		list, err := new(model.AdminIPAllowlist).GetAll(ctx, dbs.Admin)
+		if err != nil {
+			zapx.ErrorCtx(ctx, "get ip allowlist failed", zap.Error(err))
+		}
+		return list, err
+	})
+}
+
+func expired(expiresAt *time.Time, now time.Time) bool {
+	return expiresAt != nil && !now.Before(*expiresAt)
+}
+
+// activePrefixes 未过期的白名单网段
+func activePrefixes(ctx context.Context, list []*model.AdminIPAllowlist, now time.Time) []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(list))
+	for _, a := range list {
+		if expired(a.ExpiresAt, now) {
+			continue
+		}
+		p, err := ParsePrefix(a.CIDR)
+		if err != nil {
+			zapx.WarnCtx(ctx, "invalid allowlist cidr", zap.Int64("id", a.ID), zap.String("cidr", a.CIDR))
+			continue
+		}
+		prefixes = append(prefixes, p)
+	}
+	return prefixes
+}
+
+// legacyWhitelist 读取旧版ip_whitelist配置，测试时替换
+var legacyWhitelist = func(ctx context.Context) string {
+	return conf_service.String(ctx, confLegacyWhitelist, "")
+}
+
+// legacyAllowed 白名单表还没有任何记录时，按旧版ip_whitelist配置校验，配置为空时不限制
+func legacyAllowed(ctx context.Context, ip string) bool {
+	val := strings.TrimSpace(legacyWhitelist(ctx))
+	if val == "" {
+		return true
+	}
+	zapx.WarnCtx(ctx, "using legacy ip_whitelist, please migrate it to the ip allowlist")
+	for _, pattern := range strings.Split(val, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if p, err := ParsePrefix(pattern); err == nil {
+			if addr, ok := parseAddr(ip); ok && p.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			zapx.WarnCtx(ctx, "invalid legacy ip_whitelist pattern", zap.String("pattern", pattern))
+			continue
+		}
+		if re.MatchString(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowlistItem 白名单信息
+type AllowlistItem struct {
+	*model.AdminIPAllowlist
+	Expired bool `json:"expired"`
+}
+
+// ListAllowlist 列出全部白名单
+func ListAllowlist(ctx context.Context) ([]*AllowlistItem, error) {
+	list, err := new(model.AdminIPAllowlist).GetAll(ctx, dbs.Admin)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get ip allowlist failed", zap.Error(err))
+		return nil, err
+	}
+	now := time.Now()
+	items := make([]*AllowlistItem, 0, len(list))
+	for _, a := range list {
+		items = append(items, &AllowlistItem{
+			AdminIPAllowlist: a,
+			Expired:          expired(a.ExpiresAt, now),
+		})
+	}
+	return items, nil
+}
+
+// AllowlistReq 新增/修改白名单请求
+type AllowlistReq struct {
+	ID        int64  `json:"id"`                      // 修改时必填
+	CIDR      string `json:"cidr" binding:"required"` // 如 203.0.113.0/24、2001:db8::/32 或单个IP
+	Label     string `json:"label"`                   // 备注，如 公司办公网
+	ExpiresAt int64  `json:"expires_at"`              // 过期时间戳，0表示永不过期
+}
+
+// normalize 校验并规范化请求
+func (req *AllowlistReq) normalize(now time.Time) (string, *time.Time, error) {
+	p, err := ParsePrefix(req.CIDR)
+	if err != nil {
+		return "", nil, fmt.Errorf("IP或网段格式错误: %s", req.CIDR)
+	}
+	req.Label = strings.TrimSpace(req.Label)
+	if len([]rune(req.Label)) > maxLabelLength {
+		return "", nil, fmt.Errorf("备注不能超过%d个字符", maxLabelLength)
+	}
+	if req.ExpiresAt == 0 {
+		return p.String(), nil, nil
+	}
+	t := time.Unix(req.ExpiresAt, 0)
+	if !t.After(now) {
+		return "", nil, errors.New("过期时间必须晚于当前时间")
+	}
+	return p.String(), &t, nil
+}
+
+// CreateAllowlist 新增白名单
+func CreateAllowlist(ctx context.Context, op *audit_service.Operator, req *AllowlistReq) error {
+	cidr, expiresAt, err := req.normalize(time.Now())
+	if err != nil {
+		return err
+	}
+	list, rules, err := loadAll(ctx)
+	if err != nil {
+		return err
+	}
+	a := &model.AdminIPAllowlist{
+		CIDR:      cidr,
+		Label:     req.Label,
+		ExpiresAt: expiresAt,
+		CreatedBy: op.ID,
+	}
+	if err = checkLockout(ctx, append(list, a), rules, op); err != nil {
+		return err
+	}
+	if err = a.Create(ctx, dbs.Admin); err != nil {
+		zapx.ErrorCtx(ctx, "create ip allowlist failed", zap.Error(err))
+		return err
+	}
+	allowlistCache.invalidate()
+	audit_service.Record(ctx, op, actionAllowlistCreate, strconv.FormatInt(a.ID, 10), a)
+	return nil
+}
+
+// UpdateAllowlist 修改白名单
+func UpdateAllowlist(ctx context.Context, op *audit_service.Operator, req *AllowlistReq) error {
+	if req.ID <= 0 {
+		return errors.New("empty ID")
+	}
+	cidr, expiresAt, err := req.normalize(time.Now())
+	if err != nil {
+		return err
+	}
+	list, rules, err := loadAll(ctx)
+	if err != nil {
+		return err
+	}
+	var old *model.AdminIPAllowlist
+	after := make([]*model.AdminIPAllowlist, 0, len(list))
+	for _, a := range list {
+		if a.ID != req.ID {
+			after = append(after, a)
+			continue
+		}
+		old = a
+		updated := *a
+		updated.CIDR, updated.Label, updated.ExpiresAt = cidr, req.Label, expiresAt
+		after = append(after, &updated)
+	}
+	if old == nil {
+		return errors.New("白名单不存在")
+	}
+	if err = checkLockout(ctx, after, rules, op); err != nil {
+		return err
+	}
+
+	dst := map[string]any{
+		"cidr":       cidr,
+		"label":      req.Label,
+		"expires_at": expiresAt,
+	}
+	if err = old.Update(ctx, dbs.Admin, req.ID, dst); err != nil {
+		zapx.ErrorCtx(ctx, "update ip allowlist failed", zap.Error(err))
+		return err
+	}
+	allowlistCache.invalidate()
+	audit_service.Record(ctx, op, actionAllowlistUpdate, strconv.FormatInt(req.ID, 10), map[string]any{
+		"before": old,
+		"after":  dst,
+	})
+	return nil
+}
+
+// DeleteAllowlist 删除白名单
+func DeleteAllowlist(ctx context.Context, op *audit_service.Operator, id int64) error {
+	a := new(model.AdminIPAllowlist)
+	if err := a.GetByID(ctx, dbs.Admin, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("白名单不存在")
+		}
+		zapx.ErrorCtx(ctx, "get ip allowlist failed", zap.Error(err))
+		return err
+	}
+	list, rules, err := loadAll(ctx)
+	if err != nil {
+		return err
+	}
+	after := make([]*model.AdminIPAllowlist, 0, len(list))
+	for _, l := range list {
+		if l.ID != id {
+			after = append(after, l)
+		}
+	}
+	if err = checkLockout(ctx, after, rules, op); err != nil {
+		return err
+	}
+	if err = a.Delete(ctx, dbs.Admin, id); err != nil {
+		zapx.ErrorCtx(ctx, "delete ip allowlist failed", zap.Error(err))
+		return err
+	}
+	allowlistCache.invalidate()
+	audit_service.Record(ctx, op, actionAllowlistDelete, strconv.FormatInt(id, 10), a)
+	return nil
+}
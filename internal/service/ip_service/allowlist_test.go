@@ -0,0 +1,84 @@
+package ip_service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParsePrefix(t *testing.T) {
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{"10.0.0.1", "10.0.0.1/32"},
+		{" 10.0.0.1 ", "10.0.0.1/32"},
+		{"10.0.0.9/24", "10.0.0.0/24"},
+		{"2001:db8::1", "2001:db8::1/128"},
+		{"2001:db8::1/32", "2001:db8::/32"},
+		{"::ffff:10.0.0.1", "10.0.0.1/32"},
+		{"::ffff:10.0.0.0/120", "10.0.0.0/24"},
+		{"::ffff:0.0.0.0/80", "0.0.0.0/0"},
+	}
+	for _, tc := range cases {
+		p, err := ParsePrefix(tc.in)
+		if err != nil {
+			t.Errorf("ParsePrefix(%q) err = %v", tc.in, err)
+			continue
+		}
+		if p.String() != tc.want {
+			t.Errorf("ParsePrefix(%q) = %s, want %s", tc.in, p, tc.want)
+		}
+	}
+
+	for _, in := range []string{"", "10.0.0", "10.0.0.0/33", "example.com", "10.0.0.*"} {
+		if _, err := ParsePrefix(in); err == nil {
+			t.Errorf("ParsePrefix(%q) should fail", in)
+		}
+	}
+}
+
+func TestParseAddrUnmaps(t *testing.T) {
+	p, _ := ParsePrefix("10.0.0.0/24")
+	for _, ip := range []string{"10.0.0.7", "::ffff:10.0.0.7"} {
+		addr, ok := parseAddr(ip)
+		if !ok || !p.Contains(addr) {
+			t.Errorf("parseAddr(%q) = %v, %v, want inside %s", ip, addr, ok, p)
+		}
+	}
+	if _, ok := parseAddr("not-an-ip"); ok {
+		t.Error("parseAddr should reject invalid ip")
+	}
+}
+
+// setLegacyWhitelist 替换旧版ip_whitelist配置
+func setLegacyWhitelist(t *testing.T, val string) {
+	t.Helper()
+	prev := legacyWhitelist
+	legacyWhitelist = func(context.Context) string { return val }
+	t.Cleanup(func() { legacyWhitelist = prev })
+}
+
+func TestLegacyAllowed(t *testing.T) {
+	cases := []struct {
+		name string
+		conf string
+		ip   string
+		want bool
+	}{
+		{"empty conf", "", "8.8.8.8", true},
+		{"exact ip", "10.0.0.1,10.0.0.2", "10.0.0.2", true},
+		{"cidr", "10.0.0.0/24", "10.0.0.99", true},
+		{"cidr mapped ip", "10.0.0.0/24", "::ffff:10.0.0.99", true},
+		{"regex", `^192\.168\.`, "192.168.1.1", true},
+		{"no match", "10.0.0.0/24, ^192\\.168\\.", "8.8.8.8", false},
+		{"invalid pattern skipped", "(,10.0.0.1", "10.0.0.1", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			setLegacyWhitelist(t, tc.conf)
+			if got := legacyAllowed(context.Background(), tc.ip); got != tc.want {
+				t.Fatalf("legacyAllowed(%q) = %v, want %v", tc.ip, got, tc.want)
+			}
+		})
+	}
+}
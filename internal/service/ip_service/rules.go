@@ -0,0 +1,336 @@
+package ip_service
+
+import (
+	"admin/internal/model"
+	"admin/internal/service/audit_service"
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"time"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 审计日志操作类型
+const (
+	actionRuleCreate = "ip_rule.create"
+	actionRuleUpdate = "ip_rule.update"
+	actionRuleDelete = "ip_rule.delete"
+)
+
+var ruleCache cache[model.AdminIPRule]
+
+// InvalidateRules 规则在其他地方被修改后清除进程内缓存
+func InvalidateRules() {
+	ruleCache.invalidate()
+}
+
+func loadRules(ctx context.Context) ([]*model.AdminIPRule, error) {
+	return ruleCache.load(func() ([]*model.AdminIPRule, error) {
+		list, err := new(model.AdminIPRule).GetAll(ctx, dbs.Admin)
+		if err != nil {
+			zapx.ErrorCtx(ctx, "get ip rules failed", zap.Error(err))
+		}
+		return list, err
+	})
+}
+
+// loadAll 从数据库读取最新的白名单和规则，用于变更前的校验
+func loadAll(ctx context.Context) ([]*model.AdminIPAllowlist, []*model.AdminIPRule, error) {
+	list, err := new(model.AdminIPAllowlist).GetAll(ctx, dbs.Admin)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get ip allowlist failed", zap.Error(err))
+		return nil, nil, err
+	}
+	rules, err := new(model.AdminIPRule).GetAll(ctx, dbs.Admin)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get ip rules failed", zap.Error(err))
+		return nil, nil, err
+	}
+	return list, rules, nil
+}
+
+// rulePrefixes 某个角色或管理员未过期的规则网段
+func rulePrefixes(ctx context.Context, rules []*model.AdminIPRule, level string, targetID int64, now time.Time) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for _, r := range rules {
+		if r.Level != level || r.TargetID != targetID || expired(r.ExpiresAt, now) {
+			continue
+		}
+		p, err := ParsePrefix(r.CIDR)
+		if err != nil {
+			zapx.WarnCtx(ctx, "invalid ip rule cidr", zap.Int64("id", r.ID), zap.String("cidr", r.CIDR))
+			continue
+		}
+		prefixes = append(prefixes, p)
+	}
+	return prefixes
+}
+
+// restrictions 管理员需要同时满足的各级网段：全局白名单、角色规则和管理员规则，
+// 每一级只能进一步收窄访问范围，没有生效记录的级别不限制
+func restrictions(ctx context.Context, list []*model.AdminIPAllowlist, rules []*model.AdminIPRule, adminID int64, roleID int, now time.Time) [][]netip.Prefix {
+	levels := [][]netip.Prefix{
+		activePrefixes(ctx, list, now),
+		rulePrefixes(ctx, rules, model.IPRuleLevelRole, int64(roleID), now),
+		rulePrefixes(ctx, rules, model.IPRuleLevelAdmin, adminID, now),
+	}
+	active := levels[:0]
+	for _, prefixes := range levels {
+		if len(prefixes) > 0 {
+			active = append(active, prefixes)
+		}
+	}
+	return active
+}
+
+// allowed 白名单表为空时旧版ip_whitelist代替全局白名单，IP需满足每一级的限制
+func allowed(ctx context.Context, list []*model.AdminIPAllowlist, rules []*model.AdminIPRule, adminID int64, roleID int, ip string) bool {
+	if len(list) == 0 && !legacyAllowed(ctx, ip) {
+		return false
+	}
+	levels := restrictions(ctx, list, rules, adminID, roleID, time.Now())
+	if len(levels) == 0 {
+		return true
+	}
+	addr, ok := parseAddr(ip)
+	if !ok {
+		return false
+	}
+	for _, prefixes := range levels {
+		if !matchAny(prefixes, addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// Allowed 管理员能否从该IP访问，没有生效的规则时不限制
+func Allowed(ctx context.Context, adminID int64, roleID int, ip string) (bool, error) {
+	list, err := loadAllowlist(ctx)
+	if err != nil {
+		return false, err
+	}
+	rules, err := loadRules(ctx)
+	if err != nil {
+		return false, err
+	}
+	return allowed(ctx, list, rules, adminID, roleID, ip), nil
+}
+
+// checkLockout 变更后操作者当前IP必须仍然允许访问，防止把自己（以及所有人）锁在外面
+func checkLockout(ctx context.Context, list []*model.AdminIPAllowlist, rules []*model.AdminIPRule, op *audit_service.Operator) error {
+	if allowed(ctx, list, rules, op.ID, op.RoleID, op.IP) {
+		return nil
+	}
+	return fmt.Errorf("操作后当前IP(%s)将无法访问，请先添加当前IP所在网段", op.IP)
+}
+
+// checkNotSelf 不能修改作用于自己或自己所属角色的规则
+func checkNotSelf(op *audit_service.Operator, level string, targetID int64) error {
+	if (level == model.IPRuleLevelAdmin && targetID == op.ID) ||
+		(level == model.IPRuleLevelRole && targetID == int64(op.RoleID)) {
+		return errors.New("不能修改作用于自己或所属角色的IP限制规则")
+	}
+	return nil
+}
+
+// RuleReq 新增/修改IP限制规则请求
+type RuleReq struct {
+	AllowlistReq
+	Level    string `json:"level"`     // role或admin，修改时不可变更
+	TargetID int64  `json:"target_id"` // 角色ID或管理员ID，修改时不可变更
+}
+
+// checkTarget 校验规则的作用对象是否存在
+func checkTarget(ctx context.Context, level string, targetID int64) error {
+	if targetID <= 0 {
+		return errors.New("empty target ID")
+	}
+	switch level {
+	case model.IPRuleLevelRole:
+		exists, err := new(model.Role).Exists(ctx, dbs.Admin, int(targetID))
+		if err != nil {
+			zapx.ErrorCtx(ctx, "check role exists failed", zap.Error(err))
+			return err
+		}
+		if !exists {
+			return errors.New("角色不存在")
+		}
+	case model.IPRuleLevelAdmin:
+		if err := new(model.Admin).GetByID(ctx, dbs.Admin, targetID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("管理员不存在")
+			}
+			zapx.ErrorCtx(ctx, "get admin failed", zap.Error(err))
+			return err
+		}
+	default:
+		return fmt.Errorf("不支持的规则级别: %s", level)
+	}
+	return nil
+}
+
+// ListRulesReq 查询IP限制规则请求，不填时返回全部
+type ListRulesReq struct {
+	Level    string `json:"level"`
+	TargetID int64  `json:"target_id"`
+}
+
+// RuleItem 规则信息
+type RuleItem struct {
+	*model.AdminIPRule
+	Expired bool `json:"expired"`
+}
+
+// ListRules 列出IP限制规则
+func ListRules(ctx context.Context, req *ListRulesReq) ([]*RuleItem, error) {
+	rules, err := new(model.AdminIPRule).GetAll(ctx, dbs.Admin)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get ip rules failed", zap.Error(err))
+		return nil, err
+	}
+	now := time.Now()
+	items := make([]*RuleItem, 0, len(rules))
+	for _, r := range rules {
+		if req.Level != "" && r.Level != req.Level {
+			continue
+		}
+		if req.TargetID > 0 && r.TargetID != req.TargetID {
+			continue
+		}
+		items = append(items, &RuleItem{
+			AdminIPRule: r,
+			Expired:     expired(r.ExpiresAt, now),
+		})
+	}
+	return items, nil
+}
+
+// CreateRule 新增IP限制规则
+func CreateRule(ctx context.Context, op *audit_service.Operator, req *RuleReq) error {
+	cidr, expiresAt, err := req.normalize(time.Now())
+	if err != nil {
+		return err
+	}
+	if err = checkNotSelf(op, req.Level, req.TargetID); err != nil {
+		return err
+	}
+	if err = checkTarget(ctx, req.Level, req.TargetID); err != nil {
+		return err
+	}
+	list, rules, err := loadAll(ctx)
+	if err != nil {
+		return err
+	}
+	r := &model.AdminIPRule{
+		Level:     req.Level,
+		TargetID:  req.TargetID,
+		CIDR:      cidr,
+		Label:     req.Label,
+		ExpiresAt: expiresAt,
+		CreatedBy: op.ID,
+	}
+	if err = checkLockout(ctx, list, append(rules, r), op); err != nil {
+		return err
+	}
+	if err = r.Create(ctx, dbs.Admin); err != nil {
+		zapx.ErrorCtx(ctx, "create ip rule failed", zap.Error(err))
+		return err
+	}
+	ruleCache.invalidate()
+	audit_service.Record(ctx, op, actionRuleCreate, strconv.FormatInt(r.ID, 10), r)
+	return nil
+}
+
+// UpdateRule 修改IP限制规则的网段、备注和过期时间
+func UpdateRule(ctx context.Context, op *audit_service.Operator, req *RuleReq) error {
+	if req.ID <= 0 {
+		return errors.New("empty ID")
+	}
+	cidr, expiresAt, err := req.normalize(time.Now())
+	if err != nil {
+		return err
+	}
+	list, rules, err := loadAll(ctx)
+	if err != nil {
+		return err
+	}
+	var old *model.AdminIPRule
+	after := make([]*model.AdminIPRule, 0, len(rules))
+	for _, r := range rules {
+		if r.ID != req.ID {
+			after = append(after, r)
+			continue
+		}
+		old = r
+		updated := *r
+		updated.CIDR, updated.Label, updated.ExpiresAt = cidr, req.Label, expiresAt
+		after = append(after, &updated)
+	}
+	if old == nil {
+		return errors.New("规则不存在")
+	}
+	if err = checkNotSelf(op, old.Level, old.TargetID); err != nil {
+		return err
+	}
+	if err = checkLockout(ctx, list, after, op); err != nil {
+		return err
+	}
+
+	dst := map[string]any{
+		"cidr":       cidr,
+		"label":      req.Label,
+		"expires_at": expiresAt,
+	}
+	if err = old.Update(ctx, dbs.Admin, req.ID, dst); err != nil {
+		zapx.ErrorCtx(ctx, "update ip rule failed", zap.Error(err))
+		return err
+	}
+	ruleCache.invalidate()
+	audit_service.Record(ctx, op, actionRuleUpdate, strconv.FormatInt(req.ID, 10), map[string]any{
+		"before": old,
+		"after":  dst,
+	})
+	return nil
+}
+
+// DeleteRule 删除IP限制规则
+func DeleteRule(ctx context.Context, op *audit_service.Operator, id int64) error {
+	r := new(model.AdminIPRule)
+	if err := r.GetByID(ctx, dbs.Admin, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("规则不存在")
+		}
+		zapx.ErrorCtx(ctx, "get ip rule failed", zap.Error(err))
+		return err
+	}
+	if err := checkNotSelf(op, r.Level, r.TargetID); err != nil {
+		return err
+	}
+	list, rules, err := loadAll(ctx)
+	if err != nil {
+		return err
+	}
+	after := make([]*model.AdminIPRule, 0, len(rules))
+	for _, l := range rules {
+		if l.ID != id {
+			after = append(after, l)
+		}
+	}
+	if err = checkLockout(ctx, list, after, op); err != nil {
+		return err
+	}
+	if err = r.Delete(ctx, dbs.Admin, id); err != nil {
+		zapx.ErrorCtx(ctx, "delete ip rule failed", zap.Error(err))
+		return err
+	}
+	ruleCache.invalidate()
+	audit_service.Record(ctx, op, actionRuleDelete, strconv.FormatInt(id, 10), r)
+	return nil
+}
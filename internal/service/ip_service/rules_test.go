@@ -0,0 +1,144 @@
+package ip_service
+
+import (
+	"admin/internal/model"
+	"admin/internal/service/audit_service"
+	"context"
+	"testing"
+	"time"
+)
+
+const (
+	testAdminID = 7
+	testRoleID  = 3
+)
+
+func allowEntry(cidr string, expiresAt *time.Time) *model.AdminIPAllowlist {
+	return &model.AdminIPAllowlist{CIDR: cidr, ExpiresAt: expiresAt}
+}
+
+func ruleEntry(level string, targetID int64, cidr string, expiresAt *time.Time) *model.AdminIPRule {
+	return &model.AdminIPRule{Level: level, TargetID: targetID, CIDR: cidr, ExpiresAt: expiresAt}
+}
+
+func TestAllowed(t *testing.T) {
+	setLegacyWhitelist(t, "")
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	global := []*model.AdminIPAllowlist{allowEntry("10.0.0.0/8", nil)}
+
+	cases := []struct {
+		name  string
+		list  []*model.AdminIPAllowlist
+		rules []*model.AdminIPRule
+		ip    string
+		want  bool
+	}{
+		{"no restrictions", nil, nil, "8.8.8.8", true},
+		{"global allows", global, nil, "10.1.2.3", true},
+		{"global rejects", global, nil, "8.8.8.8", false},
+		{"global mapped ip", global, nil, "::ffff:10.1.2.3", true},
+		{"invalid ip", global, nil, "bogus", false},
+		{"role narrows global", global, []*model.AdminIPRule{
+			ruleEntry(model.IPRuleLevelRole, testRoleID, "10.1.0.0/16", nil),
+		}, "10.2.0.1", false},
+		{"role inside global", global, []*model.AdminIPRule{
+			ruleEntry(model.IPRuleLevelRole, testRoleID, "10.1.0.0/16", nil),
+		}, "10.1.0.1", true},
+		{"role cannot widen global", global, []*model.AdminIPRule{
+			ruleEntry(model.IPRuleLevelRole, testRoleID, "192.168.0.0/16", nil),
+		}, "192.168.0.1", false},
+		{"admin narrows role", global, []*model.AdminIPRule{
+			ruleEntry(model.IPRuleLevelRole, testRoleID, "10.1.0.0/16", nil),
+			ruleEntry(model.IPRuleLevelAdmin, testAdminID, "10.1.1.0/24", nil),
+		}, "10.1.2.1", false},
+		{"admin inside role", global, []*model.AdminIPRule{
+			ruleEntry(model.IPRuleLevelRole, testRoleID, "10.1.0.0/16", nil),
+			ruleEntry(model.IPRuleLevelAdmin, testAdminID, "10.1.1.0/24", nil),
+		}, "10.1.1.1", true},
+		{"admin rule without global", nil, []*model.AdminIPRule{
+			ruleEntry(model.IPRuleLevelAdmin, testAdminID, "10.1.1.0/24", nil),
+		}, "8.8.8.8", false},
+		{"rules of other targets ignored", global, []*model.AdminIPRule{
+			ruleEntry(model.IPRuleLevelRole, testRoleID+1, "10.1.0.0/16", nil),
+			ruleEntry(model.IPRuleLevelAdmin, testAdminID+1, "10.1.1.0/24", nil),
+			ruleEntry(model.IPRuleLevelAdmin, testRoleID, "10.1.1.0/24", nil),
+		}, "10.2.0.1", true},
+		{"expired global ignored", []*model.AdminIPAllowlist{
+			allowEntry("10.0.0.0/8", nil),
+			allowEntry("192.168.0.0/16", &past),
+		}, nil, "192.168.0.1", false},
+		{"unexpired global", []*model.AdminIPAllowlist{
+			allowEntry("10.0.0.0/8", nil),
+			allowEntry("192.168.0.0/16", &future),
+		}, nil, "192.168.0.1", true},
+		{"expired rule ignored", global, []*model.AdminIPRule{
+			ruleEntry(model.IPRuleLevelAdmin, testAdminID, "10.1.1.0/24", &past),
+		}, "10.2.0.1", true},
+		{"all global entries expired", []*model.AdminIPAllowlist{
+			allowEntry("10.0.0.0/8", &past),
+		}, nil, "8.8.8.8", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := allowed(context.Background(), tc.list, tc.rules, testAdminID, testRoleID, tc.ip); got != tc.want {
+				t.Fatalf("allowed(%s) = %v, want %v", tc.ip, got, tc.want)
+			}
+		})
+	}
+}
+
+// 旧版ip_whitelist只在白名单表为空时代替全局白名单
+func TestAllowedLegacyFallback(t *testing.T) {
+	setLegacyWhitelist(t, "192.168.0.0/16")
+	ctx := context.Background()
+
+	if !allowed(ctx, nil, nil, testAdminID, testRoleID, "192.168.1.1") {
+		t.Fatal("ip in legacy whitelist should be allowed while the table is empty")
+	}
+	if allowed(ctx, nil, nil, testAdminID, testRoleID, "10.0.0.1") {
+		t.Fatal("ip outside legacy whitelist should be rejected while the table is empty")
+	}
+	rules := []*model.AdminIPRule{ruleEntry(model.IPRuleLevelAdmin, testAdminID, "192.168.1.0/24", nil)}
+	if allowed(ctx, nil, rules, testAdminID, testRoleID, "192.168.2.1") {
+		t.Fatal("admin rule should still narrow the legacy whitelist")
+	}
+
+	list := []*model.AdminIPAllowlist{allowEntry("10.0.0.0/8", nil)}
+	if !allowed(ctx, list, nil, testAdminID, testRoleID, "10.0.0.1") {
+		t.Fatal("allowlist table should replace the legacy whitelist")
+	}
+	if allowed(ctx, list, nil, testAdminID, testRoleID, "192.168.1.1") {
+		t.Fatal("legacy whitelist should be ignored once the table has entries")
+	}
+}
+
+func TestCheckLockout(t *testing.T) {
+	setLegacyWhitelist(t, "")
+	ctx := context.Background()
+	op := &audit_service.Operator{ID: testAdminID, RoleID: testRoleID, IP: "10.1.1.1"}
+
+	cases := []struct {
+		name  string
+		list  []*model.AdminIPAllowlist
+		rules []*model.AdminIPRule
+		ok    bool
+	}{
+		{"operator ip kept", []*model.AdminIPAllowlist{allowEntry("10.0.0.0/8", nil)}, nil, true},
+		{"allowlist excludes operator", []*model.AdminIPAllowlist{allowEntry("192.168.0.0/16", nil)}, nil, false},
+		{"role rule excludes operator", nil, []*model.AdminIPRule{
+			ruleEntry(model.IPRuleLevelRole, testRoleID, "10.2.0.0/16", nil),
+		}, false},
+		{"rule for other role", nil, []*model.AdminIPRule{
+			ruleEntry(model.IPRuleLevelRole, testRoleID+1, "10.2.0.0/16", nil),
+		}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkLockout(ctx, tc.list, tc.rules, op)
+			if (err == nil) != tc.ok {
+				t.Fatalf("checkLockout() err = %v, want ok=%v", err, tc.ok)
+			}
+		})
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 	"wallet/common-lib/zapx"
 
@@ -14,56 +16,171 @@ import (
 	"wallet/common-lib/rdb"
 
 	"go.uber.org/zap"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
 const cacheKeyPrefix = "admin.perms:"
 const cacheExpireSeconds = 3600 * time.Second
 
+// denyPrefix 缓存中排除项的前缀，与授予项存放在同一个集合中
+const denyPrefix = "!"
+
 func CheckPerms(ctx context.Context, uid int64, code auth.PermCode) bool {
-	perms, err := UserPerms(ctx, uid)
+	set, err := UserPerms(ctx, uid)
 	if err != nil {
 		zapx.ErrorCtx(ctx, "userPerms error", zap.Error(err))
 		return false
 	}
-	for _, v := range perms {
-		if v == string(code) {
-			return true
-		}
-	}
-	return false
+	return set.Allows(string(code))
 }
 
-func UserPerms(ctx context.Context, uid int64) ([]string, error) {
+// UserPerms 管理员的有效权限：角色权限 ∪ 额外授予，排除优先；授予和排除都可以是通配符
+func UserPerms(ctx context.Context, uid int64) (*auth.PermSet, error) {
 	cacheKey := fmt.Sprintf("%s%d", cacheKeyPrefix, uid)
 
 	cached, err := rdb.Client.SMembers(ctx, cacheKey).Result()
 	if err == nil && len(cached) > 0 {
-		return cached, nil
+		set := &auth.PermSet{Grants: []string{}, Denies: []string{}}
+		// 缓存可能是升级前写入的，同样转换旧版权限码
+		for _, v := range cached {
+			if deny, ok := strings.CutPrefix(v, denyPrefix); ok {
+				set.Denies = append(set.Denies, auth.NormalizePerm(deny))
+			} else {
+				set.Grants = append(set.Grants, auth.NormalizePerm(v))
+			}
+		}
+		return set, nil
+	}
+
+	detail, err := UserPermDetail(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	set := detail.Set
+
+	members := make([]any, 0, len(set.Grants)+len(set.Denies))
+	for _, perm := range set.Grants {
+		members = append(members, perm)
+	}
+	for _, perm := range set.Denies {
+		members = append(members, denyPrefix+perm)
+	}
+	if len(members) > 0 {
+		if err = rdb.Client.SAdd(ctx, cacheKey, members...).Err(); err != nil {
+			zapx.ErrorCtx(ctx, "sAdd perm cache error", zap.Error(err))
+		}
+		rdb.Client.Expire(ctx, cacheKey, cacheExpireSeconds)
 	}
 
+	return set, nil
+}
+
+// PermDetail 管理员权限的组成
+type PermDetail struct {
+	RoleID    int           `json:"role_id"`
+	Role      []string      `json:"role"`      // 角色权限
+	Grants    []string      `json:"grants"`    // 额外授予
+	Denies    []string      `json:"denies"`    // 排除
+	Effective []string      `json:"effective"` // 展开后的有效权限码
+	Set       *auth.PermSet `json:"-"`
+}
+
+// UserPermDetail 查询管理员的角色权限、额外授予、排除和最终有效的权限，超级管理员固定拥有全部权限
+func UserPermDetail(ctx context.Context, uid int64) (*PermDetail, error) {
+	detail := &PermDetail{
+		Role:      []string{},
+		Grants:    []string{},
+		Denies:    []string{},
+		Effective: []string{},
+		Set:       &auth.PermSet{Grants: []string{}, Denies: []string{}},
+	}
+	admin := new(model.Admin)
+	if err := admin.GetByID(ctx, dbs.Admin, uid); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return detail, nil
+		}
+		return nil, err
+	}
+	detail.RoleID = admin.RoleID
+
+	role, err := RolePerms(ctx, admin.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	detail.Role = role
+
 	var userPerm model.AdminPerm
 	err = userPerm.GetByUID(ctx, dbs.Admin, uid)
-	if err != nil {
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		if detail.Grants, err = decodePerms(userPerm.Perms); err != nil {
+			return nil, err
+		}
+		if detail.Denies, err = decodePerms(userPerm.Denies); err != nil {
+			return nil, err
+		}
+	}
+
+	if admin.RoleID == auth.SuperAdminRoleID {
+		detail.Set.Grants = []string{auth.PermWildcard}
+	} else {
+		detail.Set.Grants = dedupPerms(slices.Concat(detail.Role, detail.Grants))
+		detail.Set.Denies = detail.Denies
+	}
+	detail.Effective = detail.Set.Expand()
+	return detail, nil
+}
+
+// RolePerms 角色的权限模板
+func RolePerms(ctx context.Context, roleID int) ([]string, error) {
+	var rolePerm model.RolePerm
+	if err := rolePerm.GetByRoleID(ctx, dbs.Admin, roleID); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return []string{}, nil
 		}
 		return nil, err
 	}
+	return decodePerms(rolePerm.Perms)
+}
 
-	var perms []string
-	if err := json.Unmarshal(userPerm.Perms, &perms); err != nil {
+func decodePerms(data datatypes.JSON) ([]string, error) {
+	perms := []string{}
+	if len(data) == 0 {
+		return perms, nil
+	}
+	if err := json.Unmarshal(data, &perms); err != nil {
 		return nil, err
 	}
+	return dedupPerms(perms), nil
+}
 
+// dedupPerms 转换旧版权限码并去重排序
+func dedupPerms(perms []string) []string {
+	list := make([]string, 0, len(perms))
 	for _, perm := range perms {
-		if err = rdb.Client.SAdd(ctx, cacheKey, perm).Err(); err != nil {
-			zapx.ErrorCtx(ctx, "sAdd perm cache error", zap.Error(err))
-		}
+		list = append(list, auth.NormalizePerm(perm))
 	}
-	rdb.Client.Expire(ctx, cacheKey, cacheExpireSeconds)
+	slices.Sort(list)
+	return slices.Compact(list)
+}
 
-	return perms, nil
+func encodePerms(perms []string) (datatypes.JSON, error) {
+	if perms == nil {
+		perms = []string{}
+	}
+	return json.Marshal(perms)
+}
+
+func validatePerms(perms []string) error {
+	for _, perm := range perms {
+		if !auth.IsValidPerm(auth.PermCode(perm)) {
+			return fmt.Errorf("invalid permission: %s", perm)
+		}
+	}
+	return nil
 }
 
 func InvalidateCache(ctx context.Context, uid int64) error {
@@ -71,41 +188,100 @@ func InvalidateCache(ctx context.Context, uid int64) error {
 	return rdb.Client.Del(ctx, cacheKey).Err()
 }
 
-func UpdateUserPermissions(ctx context.Context, currentUID, targetUID int64, reqPerms []string) error {
-	if len(reqPerms) == 0 {
-		return errors.New("permissions cannot be empty")
+// InvalidateRoleCache 清除角色下全部管理员的权限缓存
+func InvalidateRoleCache(ctx context.Context, roleID int) error {
+	ids, err := new(model.Admin).GetIDsByRole(ctx, dbs.Admin, roleID)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, fmt.Sprintf("%s%d", cacheKeyPrefix, id))
 	}
+	return rdb.Client.Del(ctx, keys...).Err()
+}
 
-	for _, perm := range reqPerms {
-		if !auth.IsValidPerm(auth.PermCode(perm)) {
-			return fmt.Errorf("invalid permission: %s", perm)
+// checkPermChange 操作者只能授予自己可以转授的权限；解除排除等同于授予，被解除的排除项同样须在可转授范围内
+func checkPermChange(parent *auth.PermSet, oldDenies, grants, denies []string) error {
+	for _, perm := range grants {
+		if !parent.CanGrant(perm) {
+			return fmt.Errorf("insufficient permission to grant: %s", perm)
+		}
+	}
+	for _, perm := range oldDenies {
+		if !slices.Contains(denies, perm) && !parent.CanGrant(perm) {
+			return fmt.Errorf("insufficient permission to lift deny: %s", perm)
+		}
+	}
+	return nil
+}
+
+// UpdateUserPermissions 设置管理员在角色权限之外的额外授予和排除，只能授予自己拥有的权限；
+// 不能修改自己和超级管理员的权限
+func UpdateUserPermissions(ctx context.Context, currentUID, targetUID int64, grants, denies []string) error {
+	if currentUID == targetUID {
+		return errors.New("不能修改自己的权限")
+	}
+	target := new(model.Admin)
+	if err := target.GetByID(ctx, dbs.Admin, targetUID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("目标用户不存在")
+		}
+		return err
+	}
+	if target.RoleID == auth.SuperAdminRoleID {
+		return errors.New("超级管理员固定拥有全部权限，不能修改")
+	}
+
+	grants, denies = dedupPerms(grants), dedupPerms(denies)
+	if err := validatePerms(grants); err != nil {
+		return err
+	}
+	if err := validatePerms(denies); err != nil {
+		return err
+	}
+	for _, perm := range grants {
+		if slices.Contains(denies, perm) {
+			return fmt.Errorf("permission cannot be both granted and denied: %s", perm)
 		}
 	}
 
 	parentPerms, err := UserPerms(ctx, currentUID)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+	if err != nil {
 		return fmt.Errorf("failed to check current user permissions: %w", err)
 	}
 
-	permMap := make(map[string]bool)
-	for _, p := range parentPerms {
-		permMap[p] = true
+	var (
+		userPerm  = &model.AdminPerm{}
+		oldDenies []string
+	)
+	err = userPerm.GetByUID(ctx, dbs.Admin, targetUID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
 	}
-
-	for _, reqPerm := range reqPerms {
-		if !permMap[reqPerm] {
-			return fmt.Errorf("insufficient permission to grant: %s", reqPerm)
+	if err == nil {
+		if oldDenies, err = decodePerms(userPerm.Denies); err != nil {
+			return err
 		}
 	}
+	if err = checkPermChange(parentPerms, oldDenies, grants, denies); err != nil {
+		return err
+	}
 
-	permsJSON, err := json.Marshal(reqPerms)
+	grantsJSON, err := encodePerms(grants)
+	if err != nil {
+		return fmt.Errorf("failed to marshal permissions: %w", err)
+	}
+	deniesJSON, err := encodePerms(denies)
 	if err != nil {
 		return fmt.Errorf("failed to marshal permissions: %w", err)
 	}
 
-	userPerm := &model.AdminPerm{}
 	if err := dbs.Admin.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		return userPerm.CreateOrUpdate(ctx, tx, targetUID, permsJSON)
+		return userPerm.CreateOrUpdate(ctx, tx, targetUID, grantsJSON, deniesJSON)
 	}); err != nil {
 		return fmt.Errorf("failed to update permissions: %w", err)
 	}
@@ -116,3 +292,56 @@ func UpdateUserPermissions(ctx context.Context, currentUID, targetUID int64, req
 
 	return nil
 }
+
+// UpdateRolePermissions 设置角色的权限模板，仅超级管理员可操作，立即对该角色的全部管理员生效
+func UpdateRolePermissions(ctx context.Context, currentUID int64, roleID int, perms []string) error {
+	operator := new(model.Admin)
+	if err := operator.GetByID(ctx, dbs.Admin, currentUID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("操作者不存在")
+		}
+		return err
+	}
+	if operator.RoleID != auth.SuperAdminRoleID {
+		return errors.New("仅超级管理员可以修改角色权限")
+	}
+	exists, err := new(model.Role).Exists(ctx, dbs.Admin, roleID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("角色不存在")
+	}
+	perms = dedupPerms(perms)
+	if err = validatePerms(perms); err != nil {
+		return err
+	}
+
+	permsJSON, err := encodePerms(perms)
+	if err != nil {
+		return fmt.Errorf("failed to marshal permissions: %w", err)
+	}
+	if err = new(model.RolePerm).CreateOrUpdate(ctx, dbs.Admin, roleID, permsJSON); err != nil {
+		return fmt.Errorf("failed to update role permissions: %w", err)
+	}
+
+	if err = InvalidateRoleCache(ctx, roleID); err != nil {
+		zapx.ErrorCtx(ctx, "failed to invalidate role cache", zap.Int("role_id", roleID), zap.Error(err))
+	}
+	zapx.InfoCtx(ctx, "update role permissions success",
+		zap.Int64("operator_id", currentUID),
+		zap.Int("role_id", roleID),
+		zap.Strings("perms", perms))
+	return nil
+}
+
+// MenuTree 管理员有权限访问的菜单树
+func MenuTree(ctx context.Context, uid int64) ([]*auth.PermNode, error) {
+	set, err := UserPerms(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	return auth.PermTree(func(code auth.PermCode) bool {
+		return set.Allows(string(code))
+	}), nil
+}
@@ -0,0 +1,40 @@
+package perm_service
+
+import (
+	"admin/internal/common/auth"
+	"testing"
+)
+
+func TestCheckPermChange(t *testing.T) {
+	// 操作者的角色包含admin.*，但被排除了admin.delete
+	parent := &auth.PermSet{
+		Grants: []string{"admin.*", "member.list"},
+		Denies: []string{"admin.delete"},
+	}
+	cases := []struct {
+		name      string
+		oldDenies []string
+		grants    []string
+		denies    []string
+		ok        bool
+	}{
+		{"grant held perm", nil, []string{"admin.list"}, nil, true},
+		{"grant perm not held", nil, []string{"member.list.export"}, nil, false},
+		{"grant own denied perm", nil, []string{"admin.delete"}, nil, false},
+		{"grant wildcard overlapping deny", nil, []string{"admin.*"}, nil, false},
+		{"add deny", nil, nil, []string{"member.list.export"}, true},
+		{"keep deny not grantable", []string{"member.list.export"}, nil, []string{"member.list.export"}, true},
+		{"lift deny of held perm", []string{"admin.list"}, nil, nil, true},
+		{"lift deny of own denied perm", []string{"admin.delete"}, nil, nil, false},
+		{"lift deny of perm not held", []string{"member.list.export"}, []string{"admin.list"}, nil, false},
+		{"replace deny with narrower one", []string{"admin.*"}, nil, []string{"admin.delete"}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkPermChange(parent, tc.oldDenies, tc.grants, tc.denies)
+			if (err == nil) != tc.ok {
+				t.Fatalf("checkPermChange() err = %v, want ok=%v", err, tc.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package session_service
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/service/conf_service"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/netip"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+)
+
+// 会话绑定配置
+const (
+	confBindMode       = "session_bind_mode"        // 0=关闭 1=仅记录日志，不影响请求 2=拒绝请求
+	confBindSameSubnet = "session_bind_same_subnet" // 1=允许在同一/24(IPv6为/64)网段内变更IP
+)
+
+const (
+	BindOff = iota
+	BindFlag
+	BindReject
+)
+
+var ErrSessionHijacked = errors.New("登录环境发生变化，请重新登录")
+
+func uaHash(ua string) string {
+	sum := sha256.Sum256([]byte(ua))
+	return hex.EncodeToString(sum[:8])
+}
+
+// CheckBinding 校验请求的IP和User-Agent是否与登录时一致，不一致时记录日志，拒绝模式下返回错误
+func CheckBinding(ctx context.Context, u *auth.User, ip, ua string) error {
+	mode := conf_service.Int(ctx, confBindMode, BindOff)
+	if mode == BindOff {
+		return nil
+	}
+
+	var reason string
+	if u.UAHash != "" && u.UAHash != uaHash(ua) {
+		reason = "user-agent changed"
+	} else if u.LoginIP != "" && !sameNetwork(u.LoginIP, ip, conf_service.Bool(ctx, confBindSameSubnet, false)) {
+		reason = "ip changed"
+	}
+	if reason == "" {
+		return nil
+	}
+
+	zapx.WarnCtx(ctx, "session binding mismatch",
+		zap.Int64("admin_id", u.ID),
+		zap.String("reason", reason),
+		zap.String("login_ip", u.LoginIP),
+		zap.String("ip", ip),
+		zap.String("user_agent", ua))
+	if mode == BindReject {
+		return ErrSessionHijacked
+	}
+	return nil
+}
+
+// sameNetwork 判断两个IP是否相同，sameSubnet时同一/24(IPv6为/64)网段也视为相同
+func sameNetwork(a, b string, sameSubnet bool) bool {
+	if a == b {
+		return true
+	}
+	if !sameSubnet {
+		return false
+	}
+	ipA, err := netip.ParseAddr(a)
+	if err != nil {
+		return false
+	}
+	ipB, err := netip.ParseAddr(b)
+	if err != nil {
+		return false
+	}
+	ipA, ipB = ipA.Unmap(), ipB.Unmap()
+	if ipA.Is4() != ipB.Is4() {
+		return false
+	}
+	bits := 64
+	if ipA.Is4() {
+		bits = 24
+	}
+	pa, _ := ipA.Prefix(bits)
+	pb, _ := ipB.Prefix(bits)
+	return pa == pb
+}
@@ -0,0 +1,212 @@
+package session_service
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/model"
+	"admin/internal/service/conf_service"
+	"context"
+	"errors"
+	"time"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+)
+
+const (
+	confMaxSessions    = "admin_max_sessions" // 单个管理员允许的并发会话数，0表示不限制
+	defaultMaxSessions = 5
+)
+
+// Create 为管理员创建会话，超过并发上限时踢掉最早的会话；scope非空时为受限会话
+func Create(ctx context.Context, admin *model.Admin, loginIP, userAgent string, scope auth.Scope) (string, error) {
+	sid, err := auth.NewSessionID()
+	if err != nil {
+		zapx.ErrorCtx(ctx, "generate session id failed", zap.Error(err))
+		return "", err
+	}
+
+	gen, err := auth.SessionGen(ctx, admin.ID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "read session gen failed", zap.Int64("admin_id", admin.ID), zap.Error(err))
+		return "", err
+	}
+
+	now := time.Now().Unix()
+	u := &auth.User{
+		ID:         admin.ID,
+		Account:    admin.Account,
+		Role:       admin.RoleID,
+		LoginIP:    loginIP,
+		LoginAt:    now,
+		UserAgent:  userAgent,
+		LastSeenAt: now,
+		CheckedAt:  now,
+		PwdSum:     pwdSum(admin.Password),
+		UAHash:     uaHash(userAgent),
+		Scope:      scope,
+		Gen:        gen,
+	}
+	ttl := TTL(GetLimits(ctx, admin.RoleID), u, time.Unix(now, 0))
+	if err = auth.SaveSession(ctx, sid, u, ttl); err != nil {
+		zapx.ErrorCtx(ctx, "save session to redis failed", zap.Error(err))
+		return "", err
+	}
+
+	if err = evict(ctx, admin.ID); err != nil {
+		// 不影响登录流程，只记录日志
+		zapx.ErrorCtx(ctx, "evict sessions failed", zap.Int64("admin_id", admin.ID), zap.Error(err))
+	}
+	return sid, nil
+}
+
+// evict 会话数超过并发上限时踢掉最早登录的会话
+func evict(ctx context.Context, adminID int64) error {
+	limit := conf_service.Int(ctx, confMaxSessions, defaultMaxSessions)
+	if limit <= 0 {
+		return nil
+	}
+	sessions, err := live(ctx, adminID)
+	if err != nil {
+		return err
+	}
+	if len(sessions) <= limit {
+		return nil
+	}
+	for _, s := range sessions[:len(sessions)-limit] {
+		if err = auth.DelSession(ctx, adminID, s.SID); err != nil {
+			return err
+		}
+		zapx.InfoCtx(ctx, "session evicted by concurrent limit",
+			zap.Int64("admin_id", adminID),
+			zap.String("session", auth.SessionTag(s.SID)),
+			zap.String("login_ip", s.LoginIP))
+	}
+	return nil
+}
+
+// live 列出管理员未超时的会话，已超时的直接删除
+func live(ctx context.Context, adminID int64) ([]*auth.Session, error) {
+	sessions, err := auth.ListSessions(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	list := make([]*auth.Session, 0, len(sessions))
+	for _, s := range sessions {
+		if CheckTimeout(GetLimits(ctx, s.Role), s.User, now) != nil {
+			_ = auth.DelSession(ctx, adminID, s.SID)
+			continue
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// Info 会话信息
+type Info struct {
+	ID         string `json:"id"`
+	LoginIP    string `json:"login_ip"`
+	UserAgent  string `json:"user_agent"`
+	LoginAt    int64  `json:"login_at"`
+	LastSeenAt int64  `json:"last_seen_at"`
+	Current    bool   `json:"current"`
+}
+
+// List 列出管理员的全部有效会话
+func List(ctx context.Context, adminID int64, currentSID string) ([]*Info, error) {
+	sessions, err := live(ctx, adminID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "list sessions failed", zap.Int64("admin_id", adminID), zap.Error(err))
+		return nil, err
+	}
+	list := make([]*Info, 0, len(sessions))
+	for _, s := range sessions {
+		list = append(list, &Info{
+			ID:         auth.SessionTag(s.SID),
+			LoginIP:    s.LoginIP,
+			UserAgent:  s.UserAgent,
+			LoginAt:    s.LoginAt,
+			LastSeenAt: s.LastSeenAt,
+			Current:    s.SID == currentSID,
+		})
+	}
+	return list, nil
+}
+
+// Revoke 注销管理员的指定会话，id为List返回的会话标识
+func Revoke(ctx context.Context, adminID int64, id string) error {
+	sessions, err := live(ctx, adminID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "list sessions failed", zap.Int64("admin_id", adminID), zap.Error(err))
+		return err
+	}
+	for _, s := range sessions {
+		if auth.SessionTag(s.SID) != id {
+			continue
+		}
+		if err = auth.DelSession(ctx, adminID, s.SID); err != nil {
+			zapx.ErrorCtx(ctx, "delete session failed", zap.Int64("admin_id", adminID), zap.Error(err))
+			return err
+		}
+		return nil
+	}
+	return errors.New("会话不存在或已失效")
+}
+
+// RevokeOthers 注销管理员除当前会话以外的全部会话
+func RevokeOthers(ctx context.Context, adminID int64, currentSID string) (int, error) {
+	n, err := auth.DelSessions(ctx, adminID, currentSID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "delete other sessions failed", zap.Int64("admin_id", adminID), zap.Error(err))
+		return 0, err
+	}
+	return n, nil
+}
+
+// Logout 注销当前会话
+func Logout(ctx context.Context, adminID int64, sid string) error {
+	if err := auth.DelSession(ctx, adminID, sid); err != nil {
+		zapx.ErrorCtx(ctx, "delete session failed", zap.Int64("admin_id", adminID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RevokeAll 注销管理员的全部会话
+func RevokeAll(ctx context.Context, adminID int64) (int, error) {
+	n, err := auth.DelSessions(ctx, adminID, "")
+	if err != nil {
+		zapx.ErrorCtx(ctx, "delete all sessions failed", zap.Int64("admin_id", adminID), zap.Error(err))
+		return 0, err
+	}
+	return n, nil
+}
+
+// ClearScope 解除管理员指定作用域的会话限制，使其成为完整会话
+func ClearScope(ctx context.Context, adminID int64, scope auth.Scope) error {
+	sessions, err := auth.ListSessions(ctx, adminID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "list sessions failed", zap.Int64("admin_id", adminID), zap.Error(err))
+		return err
+	}
+	for _, s := range sessions {
+		if s.Scope != scope {
+			continue
+		}
+		s.Scope = ""
+		if err = auth.UpdateSession(ctx, s.SID, s.User); err != nil {
+			zapx.ErrorCtx(ctx, "update session failed", zap.Int64("admin_id", adminID), zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// Elevate 会话重新验证身份后，在until之前可以访问敏感操作
+func Elevate(ctx context.Context, sid string, until time.Time) error {
+	if err := auth.ElevateSession(ctx, sid, until); err != nil {
+		zapx.ErrorCtx(ctx, "elevate session failed", zap.String("session", auth.SessionTag(sid)), zap.Error(err))
+		return err
+	}
+	return nil
+}
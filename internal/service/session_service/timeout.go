@@ -0,0 +1,74 @@
+package session_service
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/service/conf_service"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 配置单位为秒，可按角色覆盖，如 session_idle_timeout_5 为finance角色的空闲超时
+const (
+	confIdleTimeout = "session_idle_timeout"
+	confMaxLifetime = "session_max_lifetime"
+)
+
+// 会话超时后在Redis中多保留一段时间，以便告诉前端具体是哪种超时
+const expiredKeep = time.Hour
+
+var (
+	ErrIdleTimeout    = errors.New("长时间未操作，请重新登录")
+	ErrSessionExpired = errors.New("登录已超过最长有效期，请重新登录")
+)
+
+// Limits 会话时限
+type Limits struct {
+	Idle     time.Duration // 空闲超时
+	Lifetime time.Duration // 自登录起的最长有效期
+}
+
+// GetLimits 读取角色的会话时限
+func GetLimits(ctx context.Context, role int) Limits {
+	return Limits{
+		Idle:     roleSeconds(ctx, confIdleTimeout, role, auth.SessionExpireTime),
+		Lifetime: roleSeconds(ctx, confMaxLifetime, role, auth.SessionLifetime),
+	}
+}
+
+func roleSeconds(ctx context.Context, key string, role int, def time.Duration) time.Duration {
+	n := conf_service.Int(ctx, key, int(def/time.Second))
+	n = conf_service.Int(ctx, fmt.Sprintf("%s_%d", key, role), n)
+	if n <= 0 {
+		return def
+	}
+	return time.Duration(n) * time.Second
+}
+
+// CheckTimeout 检查会话是否已空闲超时或超过最长有效期
+func CheckTimeout(l Limits, u *auth.User, now time.Time) error {
+	if now.Sub(time.Unix(u.LoginAt, 0)) >= l.Lifetime {
+		return ErrSessionExpired
+	}
+	lastSeen := u.LastSeenAt
+	if lastSeen == 0 {
+		lastSeen = u.LoginAt
+	}
+	if now.Sub(time.Unix(lastSeen, 0)) >= l.Idle {
+		return ErrIdleTimeout
+	}
+	return nil
+}
+
+// TTL 会话在Redis中的保留时间: 空闲超时与剩余有效期取小，再加上保留期
+func TTL(l Limits, u *auth.User, now time.Time) time.Duration {
+	ttl := l.Idle
+	if remain := time.Unix(u.LoginAt, 0).Add(l.Lifetime).Sub(now); remain < ttl {
+		ttl = remain
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+	return ttl + expiredKeep
+}
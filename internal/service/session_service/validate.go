@@ -0,0 +1,117 @@
+package session_service
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/model"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 会话缓存的角色/状态与admins表的最长不一致时间
+const checkInterval = time.Minute
+
+var (
+	ErrAdminDisabled   = errors.New("账号已被禁用")
+	ErrPasswordChanged = errors.New("密码已修改，请重新登录")
+)
+
+func pwdSum(hash string) string {
+	sum := sha256.Sum256([]byte(hash))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Validate 将会话与admins表核对: 账号禁用/删除或密码变更时注销会话，角色变更时刷新会话中的角色。
+// 管理员变更后会话代数递增，下一次请求立即核对；否则每checkInterval核对一次
+func Validate(ctx context.Context, sid string, u *auth.User) error {
+	now := time.Now()
+	// 先读代数再读admins表，保证读到的admins记录不早于该代数
+	gen, err := auth.SessionGen(ctx, u.ID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "read session gen failed", zap.Int64("admin_id", u.ID), zap.Error(err))
+		return err
+	}
+	if u.Gen == gen && now.Unix()-u.CheckedAt < int64(checkInterval/time.Second) {
+		return nil
+	}
+
+	admin, err := getAdmin(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	if err = check(admin, u); err != nil {
+		_ = auth.DelSession(ctx, u.ID, sid)
+		zapx.InfoCtx(ctx, "session invalidated", zap.Int64("admin_id", u.ID), zap.String("reason", err.Error()))
+		return err
+	}
+	u.Role = admin.RoleID
+	u.Gen = gen
+	u.CheckedAt = now.Unix()
+	if err = auth.MarkSessionChecked(ctx, sid, u); err != nil {
+		// 下一次请求会重新核对，只记录日志
+		zapx.ErrorCtx(ctx, "mark session checked failed", zap.Int64("admin_id", u.ID), zap.Error(err))
+	}
+	return nil
+}
+
+// Sync 管理员状态、角色或密码变更后立即同步其全部会话: 递增会话代数使其在下一次请求时重新核对，
+// 并直接注销已禁用或密码已变更的会话
+func Sync(ctx context.Context, adminID int64) error {
+	if err := auth.BumpSessionGen(ctx, adminID); err != nil {
+		zapx.ErrorCtx(ctx, "bump session gen failed", zap.Int64("admin_id", adminID), zap.Error(err))
+		return err
+	}
+	sessions, err := auth.ListSessions(ctx, adminID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "list sessions failed", zap.Int64("admin_id", adminID), zap.Error(err))
+		return err
+	}
+	if len(sessions) == 0 {
+		return nil
+	}
+	admin, err := getAdmin(ctx, adminID)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		if check(admin, s.User) == nil {
+			continue
+		}
+		if err = auth.DelSession(ctx, adminID, s.SID); err != nil {
+			zapx.ErrorCtx(ctx, "delete session failed", zap.Int64("admin_id", adminID), zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// getAdmin 查询管理员，不存在时返回nil
+func getAdmin(ctx context.Context, adminID int64) (*model.Admin, error) {
+	admin := new(model.Admin)
+	if err := admin.GetByID(ctx, dbs.Admin, adminID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		zapx.ErrorCtx(ctx, "get admin by id failed", zap.Int64("admin_id", adminID), zap.Error(err))
+		return nil, err
+	}
+	return admin, nil
+}
+
+func check(admin *model.Admin, u *auth.User) error {
+	if admin == nil || admin.Status == 0 {
+		return ErrAdminDisabled
+	}
+	if u.PwdSum != "" && u.PwdSum != pwdSum(admin.Password) {
+		return ErrPasswordChanged
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package token_service
+
+import (
+	"admin/internal/model"
+	"admin/internal/service/conf_service"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wallet/common-lib/kms"
+	"wallet/common-lib/rdb"
+	"wallet/common-lib/rpcx/kms_rpcx"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+)
+
+const (
+	confSignatureSkew    = "api_signature_skew_seconds" // 签名请求允许的时间偏差(秒)
+	defaultSignatureSkew = 300
+
+	signingSecretBytes = 32
+	nonceMaxLength     = 64
+	noncePrefix        = "admin.sign.nonce:" // 已使用的nonce，保留到时间戳过期为止
+
+	// 解密后的签名密钥在进程内缓存，避免每个请求都调用KMS
+	secretCacheTTL = 5 * time.Minute
+)
+
+var (
+	ErrSignatureRequired = errors.New("该令牌要求请求签名")
+	ErrSignatureInvalid  = errors.New("请求签名错误")
+	ErrTimestampStale    = errors.New("请求时间戳已过期")
+	ErrNonceReused       = errors.New("请求nonce已使用")
+)
+
+var secretCache sync.Map // token ID -> *cachedSecret
+
+// signatureSkew 读取签名请求允许的时间偏差(秒)，测试时替换
+var signatureSkew = func(ctx context.Context) int {
+	return conf_service.Int(ctx, confSignatureSkew, defaultSignatureSkew)
+}
+
+type cachedSecret struct {
+	secret []byte
+	at     time.Time
+}
+
+// SignedRequest 待校验的签名请求
+type SignedRequest struct {
+	Method    string
+	Path      string // 含查询参数
+	Timestamp string
+	Nonce     string
+	Signature string
+	Body      []byte
+}
+
+// StringToSign 签名原文：方法、路径、时间戳、nonce和请求体SHA-256，以换行分隔
+func (r *SignedRequest) StringToSign() string {
+	sum := sha256.Sum256(r.Body)
+	return strings.Join([]string{
+		strings.ToUpper(r.Method),
+		r.Path,
+		r.Timestamp,
+		r.Nonce,
+		hex.EncodeToString(sum[:]),
+	}, "\n")
+}
+
+// Sign 计算签名，结果为十六进制的HMAC-SHA256
+func Sign(secret []byte, r *SignedRequest) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(r.StringToSign()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newSigningSecret 生成签名密钥，返回明文和KMS密文
+func newSigningSecret(ctx context.Context, adminID int64) (string, []byte, error) {
+	secret, err := randomHex(signingSecretBytes)
+	if err != nil {
+		return "", nil, err
+	}
+	blob, _, err := kms_rpcx.Encrypt(ctx, secret, kms.PurposeAdminAPISigningSecret, adminID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "encrypt signing secret failed", zap.Error(err))
+		return "", nil, err
+	}
+	return secret, blob, nil
+}
+
+func signingSecret(ctx context.Context, t *model.AdminAPIToken) ([]byte, error) {
+	if v, ok := secretCache.Load(t.ID); ok {
+		if c := v.(*cachedSecret); time.Since(c.at) < secretCacheTTL {
+			return c.secret, nil
+		}
+	}
+	secret, err := kms_rpcx.Decrypt(ctx, t.SigningSecret, kms.PurposeAdminAPISigningSecret, t.AdminID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "decrypt signing secret failed", zap.Int64("token_id", t.ID), zap.Error(err))
+		return nil, err
+	}
+	secretCache.Store(t.ID, &cachedSecret{secret: []byte(secret), at: time.Now()})
+	return []byte(secret), nil
+}
+
+// VerifySignature 校验API令牌请求的签名；要求签名的令牌必须签名，其余令牌带了签名也会校验
+func VerifySignature(ctx context.Context, t *model.AdminAPIToken, r *SignedRequest) error {
+	if r.Signature == "" {
+		if t.Signed == 1 {
+			return ErrSignatureRequired
+		}
+		return nil
+	}
+	if len(t.SigningSecret) == 0 {
+		return ErrSignatureInvalid
+	}
+
+	skew := int64(max(signatureSkew(ctx), 1))
+	ts, err := strconv.ParseInt(r.Timestamp, 10, 64)
+	if err != nil {
+		return ErrTimestampStale
+	}
+	if d := time.Now().Unix() - ts; d > skew || d < -skew {
+		return ErrTimestampStale
+	}
+	if r.Nonce == "" || len(r.Nonce) > nonceMaxLength {
+		return ErrSignatureInvalid
+	}
+
+	secret, err := signingSecret(ctx, t)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(Sign(secret, r)), []byte(strings.ToLower(r.Signature))) {
+		zapx.WarnCtx(ctx, "api request signature mismatch", zap.Int64("token_id", t.ID), zap.String("path", r.Path))
+		return ErrSignatureInvalid
+	}
+
+	// 签名正确后才记录nonce，时间戳过期前同一nonce不能重复使用
+	key := fmt.Sprintf("%s%d:%s", noncePrefix, t.ID, r.Nonce)
+	ok, err := rdb.Client.SetNX(ctx, key, ts, time.Duration(2*skew)*time.Second).Result()
+	if err != nil {
+		zapx.ErrorCtx(ctx, "record signature nonce failed", zap.Error(err))
+		return err
+	}
+	if !ok {
+		zapx.WarnCtx(ctx, "api request nonce replayed", zap.Int64("token_id", t.ID), zap.String("nonce", r.Nonce))
+		return ErrNonceReused
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package token_service
+
+import (
+	"admin/internal/model"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"wallet/common-lib/rdb"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const testSkew = 60
+
+var testSecret = []byte("secret")
+
+// setupRedis 连接测试用的Redis（ADMIN_TEST_REDIS，默认127.0.0.1:6379），不可用时跳过
+func setupRedis(t *testing.T) context.Context {
+	t.Helper()
+	addr := os.Getenv("ADMIN_TEST_REDIS")
+	if addr == "" {
+		addr = "127.0.0.1:6379"
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	ctx := context.Background()
+	if err := client.Ping(ctx).Err(); err != nil {
+		t.Skipf("redis %s unavailable: %v", addr, err)
+	}
+	prev := rdb.Client
+	rdb.Client = client
+	t.Cleanup(func() {
+		rdb.Client = prev
+		_ = client.Close()
+	})
+	return ctx
+}
+
+// newTestToken 返回要求签名的令牌，签名密钥预先放入缓存，不调用KMS
+func newTestToken(t *testing.T) *model.AdminAPIToken {
+	t.Helper()
+	prev := signatureSkew
+	signatureSkew = func(context.Context) int { return testSkew }
+	tok := &model.AdminAPIToken{
+		ID:            rand.Int64N(1 << 40),
+		AdminID:       1,
+		Signed:        1,
+		SigningSecret: []byte("encrypted"),
+	}
+	secretCache.Store(tok.ID, &cachedSecret{secret: testSecret, at: time.Now()})
+	t.Cleanup(func() {
+		signatureSkew = prev
+		secretCache.Delete(tok.ID)
+	})
+	return tok
+}
+
+func newTestRequest(ts int64) *SignedRequest {
+	r := &SignedRequest{
+		Method:    "post",
+		Path:      "/api/v1/member/list?page=1",
+		Timestamp: strconv.FormatInt(ts, 10),
+		Nonce:     strconv.FormatUint(rand.Uint64(), 16),
+		Body:      []byte(`{"a":1}`),
+	}
+	r.Signature = Sign(testSecret, r)
+	return r
+}
+
+func TestSignVector(t *testing.T) {
+	r := &SignedRequest{
+		Method:    "post",
+		Path:      "/api/v1/member/list?page=1",
+		Timestamp: "1700000000",
+		Nonce:     "abc",
+		Body:      []byte(`{"a":1}`),
+	}
+	wantSTS := "POST\n/api/v1/member/list?page=1\n1700000000\nabc\n" +
+		"015abd7f5cc57a2dd94b7590f04ad8084273905ee33ec5cebeae62276a97f862"
+	if got := r.StringToSign(); got != wantSTS {
+		t.Fatalf("StringToSign() = %q, want %q", got, wantSTS)
+	}
+	want := "45c90ff5a4823ff35b365dc37edd8c676f0183db8ad6de9a122b1448ad6df9a1"
+	if got := Sign(testSecret, r); got != want {
+		t.Fatalf("Sign() = %s, want %s", got, want)
+	}
+}
+
+func TestVerifySignatureRejected(t *testing.T) {
+	tok := newTestToken(t)
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	cases := []struct {
+		name  string
+		token *model.AdminAPIToken
+		req   func() *SignedRequest
+		want  error
+	}{
+		{"missing signature", tok, func() *SignedRequest {
+			r := newTestRequest(now)
+			r.Signature = ""
+			return r
+		}, ErrSignatureRequired},
+		{"no signing secret", &model.AdminAPIToken{ID: tok.ID}, func() *SignedRequest {
+			return newTestRequest(now)
+		}, ErrSignatureInvalid},
+		{"bad timestamp", tok, func() *SignedRequest {
+			r := newTestRequest(now)
+			r.Timestamp = "yesterday"
+			return r
+		}, ErrTimestampStale},
+		{"too old", tok, func() *SignedRequest {
+			return newTestRequest(now - testSkew - 1)
+		}, ErrTimestampStale},
+		// 时钟往前走只会更远，+2避免跨秒误判
+		{"too far ahead", tok, func() *SignedRequest {
+			return newTestRequest(now + testSkew + 2)
+		}, ErrTimestampStale},
+		{"empty nonce", tok, func() *SignedRequest {
+			r := newTestRequest(now)
+			r.Nonce = ""
+			r.Signature = Sign(testSecret, r)
+			return r
+		}, ErrSignatureInvalid},
+		{"oversized nonce", tok, func() *SignedRequest {
+			r := newTestRequest(now)
+			r.Nonce = strings.Repeat("n", nonceMaxLength+1)
+			r.Signature = Sign(testSecret, r)
+			return r
+		}, ErrSignatureInvalid},
+		{"tampered body", tok, func() *SignedRequest {
+			r := newTestRequest(now)
+			r.Body = []byte(`{"a":2}`)
+			return r
+		}, ErrSignatureInvalid},
+		{"wrong secret", tok, func() *SignedRequest {
+			r := newTestRequest(now)
+			r.Signature = Sign([]byte("other"), r)
+			return r
+		}, ErrSignatureInvalid},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := VerifySignature(ctx, tc.token, tc.req()); !errors.Is(err, tc.want) {
+				t.Fatalf("VerifySignature() err = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+// 时间偏差边界内的请求通过时间戳校验，用错误的签名让校验停在记录nonce之前
+func TestVerifySignatureSkewWindow(t *testing.T) {
+	tok := newTestToken(t)
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	for _, ts := range []int64{now - testSkew + 1, now, now + testSkew} {
+		r := newTestRequest(ts)
+		r.Signature = Sign([]byte("other"), r)
+		if err := VerifySignature(ctx, tok, r); !errors.Is(err, ErrSignatureInvalid) {
+			t.Errorf("timestamp offset %d: err = %v, want %v", ts-now, err, ErrSignatureInvalid)
+		}
+	}
+}
+
+func TestVerifySignatureUnsigned(t *testing.T) {
+	tok := newTestToken(t)
+	tok.Signed = 0
+	r := newTestRequest(time.Now().Unix())
+	r.Signature = ""
+	if err := VerifySignature(context.Background(), tok, r); err != nil {
+		t.Fatalf("unsigned request to optional-signing token: %v", err)
+	}
+}
+
+func TestVerifySignatureNonce(t *testing.T) {
+	ctx := setupRedis(t)
+	tok := newTestToken(t)
+
+	r := newTestRequest(time.Now().Unix())
+	t.Cleanup(func() {
+		rdb.Client.Del(ctx, noncePrefix+strconv.FormatInt(tok.ID, 10)+":"+r.Nonce)
+	})
+	// 十六进制签名不区分大小写
+	r.Signature = strings.ToUpper(r.Signature)
+	if err := VerifySignature(ctx, tok, r); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	if err := VerifySignature(ctx, tok, r); !errors.Is(err, ErrNonceReused) {
+		t.Fatalf("replayed request err = %v, want %v", err, ErrNonceReused)
+	}
+}
@@ -0,0 +1,268 @@
+package token_service
+
+import (
+	"admin/internal/common/auth"
+	"admin/internal/model"
+	"admin/internal/service/audit_service"
+	"admin/internal/service/conf_service"
+	"admin/internal/service/perm_service"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"wallet/common-lib/dbs"
+	"wallet/common-lib/zapx"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	confMaxDays    = "api_token_max_days" // API令牌最长有效期(天)
+	defaultMaxDays = 90
+
+	tokenPrefix   = "adm_"
+	tokenBytes    = 32
+	displayLength = len(tokenPrefix) + 8 // 列表中展示的令牌前缀长度
+	maxNameLength = 40
+	usageThrottle = time.Minute // 最近使用记录的更新间隔，避免每个请求都写库
+)
+
+// 审计日志操作类型
+const (
+	actionCreate = "api_token.create"
+	actionRevoke = "api_token.revoke"
+)
+
+var ErrInvalidToken = errors.New("API令牌无效或已过期")
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// Principal 通过API令牌认证的服务账号
+type Principal struct {
+	Admin  *model.Admin
+	Token  *model.AdminAPIToken
+	Scopes []string
+}
+
+// Load 按令牌明文读取令牌记录，不存在时返回ErrInvalidToken
+func Load(ctx context.Context, raw string) (*model.AdminAPIToken, error) {
+	if !strings.HasPrefix(raw, tokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+	t := new(model.AdminAPIToken)
+	if err := t.GetByHash(ctx, dbs.Admin, hashToken(raw)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		zapx.ErrorCtx(ctx, "get api token failed", zap.Error(err))
+		return nil, err
+	}
+	return t, nil
+}
+
+// Authenticate 校验Load读取的API令牌，令牌须未撤销、未过期，且所属服务账号未被禁用或删除
+func Authenticate(ctx context.Context, t *model.AdminAPIToken, ip string) (*Principal, error) {
+	now := time.Now()
+	if t.RevokedAt != nil || !now.Before(t.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+	admin := new(model.Admin)
+	if err := admin.GetByID(ctx, dbs.Admin, t.AdminID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		zapx.ErrorCtx(ctx, "get token admin failed", zap.Int64("admin_id", t.AdminID), zap.Error(err))
+		return nil, err
+	}
+	if admin.Status == 0 || admin.IsService != 1 {
+		return nil, ErrInvalidToken
+	}
+	var scopes []string
+	if err := json.Unmarshal(t.Scopes, &scopes); err != nil {
+		zapx.ErrorCtx(ctx, "unmarshal api token scopes failed", zap.Int64("token_id", t.ID), zap.Error(err))
+		return nil, ErrInvalidToken
+	}
+	for i := range scopes {
+		scopes[i] = auth.NormalizePerm(scopes[i])
+	}
+
+	if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= usageThrottle || t.LastUsedIP != ip {
+		if err := t.UpdateUsage(ctx, dbs.Admin, t.ID, ip); err != nil {
+			// 不影响请求，只记录日志
+			zapx.ErrorCtx(ctx, "update api token usage failed", zap.Int64("token_id", t.ID), zap.Error(err))
+		}
+	}
+	return &Principal{Admin: admin, Token: t, Scopes: scopes}, nil
+}
+
+// ListReq 令牌列表请求
+type ListReq struct {
+	AdminID int64 `json:"admin_id"` // 服务账号筛选，不填为全部
+}
+
+// TokenItem 令牌信息，不含令牌本身
+type TokenItem struct {
+	*model.AdminAPIToken
+	Active bool `json:"active"`
+}
+
+// List 列出API令牌
+func List(ctx context.Context, req *ListReq) ([]*TokenItem, error) {
+	list, err := new(model.AdminAPIToken).GetList(ctx, dbs.Admin, req.AdminID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get api tokens failed", zap.Error(err))
+		return nil, err
+	}
+	now := time.Now()
+	items := make([]*TokenItem, 0, len(list))
+	for _, t := range list {
+		items = append(items, &TokenItem{
+			AdminAPIToken: t,
+			Active:        t.RevokedAt == nil && now.Before(t.ExpiresAt),
+		})
+	}
+	return items, nil
+}
+
+// CreateReq 创建令牌请求
+type CreateReq struct {
+	AdminID       int64    `json:"admin_id" binding:"required"`        // 服务账号ID
+	Name          string   `json:"name" binding:"required"`            // 用途，如 对账脚本
+	Scopes        []string `json:"scopes" binding:"required"`          // 权限码，须在服务账号的权限范围内
+	ExpiresInDays int      `json:"expires_in_days" binding:"required"` // 有效期(天)
+	Signed        bool     `json:"signed"`                             // 要求请求签名，同时返回签名密钥
+}
+
+// CreateResp 创建令牌响应，令牌明文只在此时返回一次
+type CreateResp struct {
+	ID            int64  `json:"id"`
+	Token         string `json:"token"`
+	SigningSecret string `json:"signing_secret,omitempty"`
+	ExpiresAt     int64  `json:"expires_at"`
+}
+
+// Create 为服务账号创建API令牌
+func Create(ctx context.Context, op *audit_service.Operator, req *CreateReq) (*CreateResp, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len([]rune(req.Name)) > maxNameLength {
+		return nil, fmt.Errorf("令牌名称不能为空且不能超过%d个字符", maxNameLength)
+	}
+	maxDays := conf_service.Int(ctx, confMaxDays, defaultMaxDays)
+	if req.ExpiresInDays <= 0 || req.ExpiresInDays > maxDays {
+		return nil, fmt.Errorf("有效期须在1到%d天之间", maxDays)
+	}
+
+	admin := new(model.Admin)
+	if err := admin.GetByID(ctx, dbs.Admin, req.AdminID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("服务账号不存在")
+		}
+		zapx.ErrorCtx(ctx, "get admin failed", zap.Error(err))
+		return nil, err
+	}
+	if admin.IsService != 1 {
+		return nil, errors.New("只能为服务账号创建API令牌")
+	}
+
+	perms, err := perm_service.UserPerms(ctx, admin.ID)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "get service account perms failed", zap.Int64("admin_id", admin.ID), zap.Error(err))
+		return nil, err
+	}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, s := range req.Scopes {
+		s = auth.NormalizePerm(s)
+		if !auth.IsValidPerm(auth.PermCode(s)) {
+			return nil, fmt.Errorf("invalid permission: %s", s)
+		}
+		if !perms.CanGrant(s) {
+			return nil, fmt.Errorf("服务账号没有该权限: %s", s)
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("令牌权限不能为空")
+	}
+	scopesJSON, err := json.Marshal(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := randomHex(tokenBytes)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "generate api token failed", zap.Error(err))
+		return nil, err
+	}
+	raw := tokenPrefix + token
+	t := &model.AdminAPIToken{
+		AdminID:   admin.ID,
+		Name:      req.Name,
+		Prefix:    raw[:displayLength],
+		TokenHash: hashToken(raw),
+		Scopes:    scopesJSON,
+		ExpiresAt: time.Now().AddDate(0, 0, req.ExpiresInDays),
+		CreatedBy: op.ID,
+	}
+	var secret string
+	if req.Signed {
+		if secret, t.SigningSecret, err = newSigningSecret(ctx, admin.ID); err != nil {
+			return nil, err
+		}
+		t.Signed = 1
+	}
+	if err = t.Create(ctx, dbs.Admin); err != nil {
+		zapx.ErrorCtx(ctx, "create api token failed", zap.Error(err))
+		return nil, err
+	}
+	audit_service.Record(ctx, op, actionCreate, strconv.FormatInt(t.ID, 10), t)
+
+	return &CreateResp{
+		ID:            t.ID,
+		Token:         raw,
+		SigningSecret: secret,
+		ExpiresAt:     t.ExpiresAt.Unix(),
+	}, nil
+}
+
+// Revoke 撤销API令牌，立即失效
+func Revoke(ctx context.Context, op *audit_service.Operator, id int64) error {
+	t := new(model.AdminAPIToken)
+	if err := t.GetByID(ctx, dbs.Admin, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("令牌不存在")
+		}
+		zapx.ErrorCtx(ctx, "get api token failed", zap.Error(err))
+		return err
+	}
+	ok, err := t.Revoke(ctx, dbs.Admin, id)
+	if err != nil {
+		zapx.ErrorCtx(ctx, "revoke api token failed", zap.Error(err))
+		return err
+	}
+	if !ok {
+		return errors.New("令牌已撤销")
+	}
+	audit_service.Record(ctx, op, actionRevoke, strconv.FormatInt(id, 10), t)
+	return nil
+}
@@ -30,9 +30,14 @@ CREATE TABLE `admins` (
     `password` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '密码',
     `role_id` INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '角色ID',
     `status` TINYINT NOT NULL DEFAULT 1 COMMENT '状态 0=禁用，1=启用',
+    `is_service` TINYINT NOT NULL DEFAULT 0 COMMENT '服务账号 0=否，1=是，服务账号不能登录，只能通过API令牌访问',
     `last_login_at` DATETIME NULL COMMENT '最近登录时间',
     `last_login_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '最近登录IP',
     `mfa_secret` VARBINARY(255) NULL COMMENT '谷歌验证器密钥',
+    `mfa_reenroll` TINYINT NOT NULL DEFAULT 0 COMMENT '下次登录必须重新绑定谷歌验证器 0=否，1=是',
+    `must_change_pwd` TINYINT NOT NULL DEFAULT 0 COMMENT '下次登录必须修改密码 0=否，1=是',
+    `pwd_changed_at` DATETIME NULL COMMENT '最近修改密码时间',
+    `deleted_at` DATETIME NULL COMMENT '删除时间',
     `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
     `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
     PRIMARY KEY (`id`) USING BTREE,
@@ -40,12 +45,159 @@ CREATE TABLE `admins` (
     KEY `idx_role_id` (`role_id`) USING BTREE
 ) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='管理员用户表';
 
+-- 历史密码表
+DROP TABLE IF EXISTS `admin_password_history`;
+CREATE TABLE `admin_password_history` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `admin_id` BIGINT UNSIGNED NOT NULL COMMENT '管理员ID',
+    `password` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '密码哈希',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `idx_admin_id` (`admin_id`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='管理员历史密码表';
+
+-- MFA恢复码表
+DROP TABLE IF EXISTS `admin_recovery_codes`;
+CREATE TABLE `admin_recovery_codes` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `admin_id` BIGINT UNSIGNED NOT NULL COMMENT '管理员ID',
+    `code_hash` CHAR(64) NOT NULL DEFAULT '' COMMENT '恢复码哈希',
+    `used_at` DATETIME NULL COMMENT '使用时间',
+    `used_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '使用IP',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    UNIQUE KEY `idx_admin_code` (`admin_id`, `code_hash`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='MFA恢复码表';
+
+-- WebAuthn凭证表
+DROP TABLE IF EXISTS `admin_webauthn_credentials`;
+CREATE TABLE `admin_webauthn_credentials` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `admin_id` BIGINT UNSIGNED NOT NULL COMMENT '管理员ID',
+    `name` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '设备名称',
+    `credential_id` VARBINARY(255) NOT NULL COMMENT '凭证ID',
+    `credential` JSON NOT NULL COMMENT '凭证(公钥、签名计数等)',
+    `last_used_at` DATETIME NULL COMMENT '最近使用时间',
+    `last_used_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '最近使用IP',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    UNIQUE KEY `idx_credential_id` (`credential_id`) USING BTREE,
+    KEY `idx_admin_id` (`admin_id`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='WebAuthn凭证表';
+
+-- 服务账号API令牌表，只保存令牌的哈希
+DROP TABLE IF EXISTS `admin_api_tokens`;
+CREATE TABLE `admin_api_tokens` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `admin_id` BIGINT UNSIGNED NOT NULL COMMENT '服务账号ID',
+    `name` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '令牌用途',
+    `prefix` VARCHAR(20) NOT NULL DEFAULT '' COMMENT '令牌前缀，用于辨认',
+    `token_hash` CHAR(64) NOT NULL COMMENT '令牌的SHA-256',
+    `scopes` JSON NOT NULL COMMENT '令牌的权限码',
+    `signed` TINYINT NOT NULL DEFAULT 0 COMMENT '是否要求请求签名 0=否，1=是',
+    `signing_secret` VARBINARY(512) NULL COMMENT '请求签名密钥（KMS加密）',
+    `expires_at` DATETIME NOT NULL COMMENT '过期时间',
+    `last_used_at` DATETIME NULL COMMENT '最近使用时间',
+    `last_used_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '最近使用IP',
+    `revoked_at` DATETIME NULL COMMENT '撤销时间',
+    `created_by` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建人ID',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    UNIQUE KEY `uk_token_hash` (`token_hash`) USING BTREE,
+    KEY `idx_admin_id` (`admin_id`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='服务账号API令牌表';
+
+-- 管理员登录记录表，只追加不修改
+DROP TABLE IF EXISTS `admin_login_logs`;
+CREATE TABLE `admin_login_logs` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `admin_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '管理员ID，账号不存在时为0',
+    `account` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '登录账号',
+    `success` TINYINT NOT NULL DEFAULT 0 COMMENT '是否成功: 1=成功 0=失败',
+    `reason` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '失败原因',
+    `ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '登录IP',
+    `user_agent` VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'User-Agent',
+    `mfa` VARCHAR(20) NOT NULL DEFAULT '' COMMENT '二次验证方式: totp/webauthn/recovery_code',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '登录时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `idx_admin_id_ip` (`admin_id`, `ip`) USING BTREE,
+    KEY `idx_account` (`account`) USING BTREE,
+    KEY `idx_created_at` (`created_at`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='管理员登录记录表';
+
+-- 登录IP白名单表，没有生效的记录时不限制登录IP
+DROP TABLE IF EXISTS `admin_ip_allowlist`;
+CREATE TABLE `admin_ip_allowlist` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `cidr` VARCHAR(50) NOT NULL DEFAULT '' COMMENT 'IP网段(CIDR)',
+    `label` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '备注',
+    `expires_at` DATETIME NULL COMMENT '过期时间，NULL为永不过期',
+    `created_by` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建人ID',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='登录IP白名单表';
+
+-- 角色/管理员IP限制规则表，与全局白名单叠加生效，登录IP需同时满足全局、角色和管理员三级限制
+DROP TABLE IF EXISTS `admin_ip_rules`;
+CREATE TABLE `admin_ip_rules` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `level` VARCHAR(10) NOT NULL DEFAULT '' COMMENT '规则级别: role/admin',
+    `target_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '角色ID或管理员ID',
+    `cidr` VARCHAR(50) NOT NULL DEFAULT '' COMMENT 'IP网段(CIDR)',
+    `label` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '备注',
+    `expires_at` DATETIME NULL COMMENT '过期时间，NULL为永不过期',
+    `created_by` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建人ID',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `idx_level_target` (`level`, `target_id`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='角色/管理员IP限制规则表';
+
+-- 管理操作审计日志表
+DROP TABLE IF EXISTS `admin_audit_logs`;
+CREATE TABLE `admin_audit_logs` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `operator_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '操作者ID',
+    `operator_account` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '操作者账号',
+    `ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '操作IP',
+    `action` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '操作类型',
+    `target` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '操作对象',
+    `detail` JSON NULL COMMENT '操作详情',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `idx_operator_id` (`operator_id`) USING BTREE,
+    KEY `idx_action` (`action`) USING BTREE,
+    KEY `idx_created_at` (`created_at`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='管理操作审计日志表';
+
+-- 角色权限模板表，管理员的有效权限 = (角色权限 ∪ 额外授予) − 排除
+-- 权限码以.分隔层级，可使用通配符如 member.* 或 *，排除优先；旧版权限码(如 member-list)读取时自动转换
+DROP TABLE IF EXISTS `role_perms`;
+CREATE TABLE `role_perms` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `role_id` INT UNSIGNED NOT NULL COMMENT '角色ID',
+    `perms` JSON NOT NULL COMMENT '权限列表JSON数组，支持通配符',
+    `created_at` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建时间戳',
+    `updated_at` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '更新时间戳',
+    PRIMARY KEY (`id`) USING BTREE,
+    UNIQUE KEY `idx_role_id` (`role_id`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='角色权限模板表';
+
+-- 默认角色的权限模板，超级管理员固定拥有全部权限；重置谷歌验证器、重置密码和修改角色权限仅超级管理员可操作
+INSERT INTO `role_perms` (`role_id`, `perms`, `created_at`, `updated_at`) VALUES
+(2, '["admin.create", "admin.perm.view", "admin.perm.edit", "role.perm.view", "agent.review.*"]', UNIX_TIMESTAMP(), UNIX_TIMESTAMP()),
+(3, '["agent.review.*"]', UNIX_TIMESTAMP(), UNIX_TIMESTAMP()),
+(4, '["agent.review.*"]', UNIX_TIMESTAMP(), UNIX_TIMESTAMP()),
+(5, '["agent.review.*"]', UNIX_TIMESTAMP(), UNIX_TIMESTAMP());
+
 -- 用户权限表
 DROP TABLE IF EXISTS `admin_perms`;
 CREATE TABLE `admin_perms` (
     `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
     `uid` BIGINT UNSIGNED NOT NULL COMMENT '用户ID',
-    `perms` JSON NOT NULL COMMENT '权限列表JSON数组',
+    `perms` JSON NOT NULL COMMENT '在角色权限之外额外授予的权限JSON数组，支持通配符',
+    `denies` JSON NULL COMMENT '从角色权限中排除的权限JSON数组，支持通配符',
     `created_at` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建时间戳',
     `updated_at` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '更新时间戳',
     PRIMARY KEY (`id`) USING BTREE,
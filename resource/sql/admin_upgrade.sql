@@ -0,0 +1,195 @@
+-- ============================================
+-- Admin 数据库升级脚本
+-- 已有数据库升级到当前版本时执行一次，不删除任何数据；新建数据库直接执行admin.sql即可
+-- ============================================
+
+USE `admin`;
+
+-- 管理员表新增字段
+ALTER TABLE `admins`
+    ADD COLUMN `is_service` TINYINT NOT NULL DEFAULT 0 COMMENT '服务账号 0=否，1=是，服务账号不能登录，只能通过API令牌访问' AFTER `status`,
+    ADD COLUMN `mfa_reenroll` TINYINT NOT NULL DEFAULT 0 COMMENT '下次登录必须重新绑定谷歌验证器 0=否，1=是' AFTER `mfa_secret`,
+    ADD COLUMN `must_change_pwd` TINYINT NOT NULL DEFAULT 0 COMMENT '下次登录必须修改密码 0=否，1=是' AFTER `mfa_reenroll`,
+    ADD COLUMN `pwd_changed_at` DATETIME NULL COMMENT '最近修改密码时间' AFTER `must_change_pwd`,
+    ADD COLUMN `deleted_at` DATETIME NULL COMMENT '删除时间' AFTER `pwd_changed_at`;
+
+-- 用户权限表新增排除项
+ALTER TABLE `admin_perms`
+    MODIFY COLUMN `perms` JSON NOT NULL COMMENT '在角色权限之外额外授予的权限JSON数组，支持通配符',
+    ADD COLUMN `denies` JSON NULL COMMENT '从角色权限中排除的权限JSON数组，支持通配符' AFTER `perms`;
+
+-- 历史密码表
+CREATE TABLE IF NOT EXISTS `admin_password_history` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `admin_id` BIGINT UNSIGNED NOT NULL COMMENT '管理员ID',
+    `password` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '密码哈希',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `idx_admin_id` (`admin_id`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='管理员历史密码表';
+
+-- MFA恢复码表
+CREATE TABLE IF NOT EXISTS `admin_recovery_codes` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `admin_id` BIGINT UNSIGNED NOT NULL COMMENT '管理员ID',
+    `code_hash` CHAR(64) NOT NULL DEFAULT '' COMMENT '恢复码哈希',
+    `used_at` DATETIME NULL COMMENT '使用时间',
+    `used_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '使用IP',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    UNIQUE KEY `idx_admin_code` (`admin_id`, `code_hash`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='MFA恢复码表';
+
+-- WebAuthn凭证表
+CREATE TABLE IF NOT EXISTS `admin_webauthn_credentials` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `admin_id` BIGINT UNSIGNED NOT NULL COMMENT '管理员ID',
+    `name` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '设备名称',
+    `credential_id` VARBINARY(255) NOT NULL COMMENT '凭证ID',
+    `credential` JSON NOT NULL COMMENT '凭证(公钥、签名计数等)',
+    `last_used_at` DATETIME NULL COMMENT '最近使用时间',
+    `last_used_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '最近使用IP',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    UNIQUE KEY `idx_credential_id` (`credential_id`) USING BTREE,
+    KEY `idx_admin_id` (`admin_id`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='WebAuthn凭证表';
+
+-- 服务账号API令牌表，只保存令牌的哈希
+CREATE TABLE IF NOT EXISTS `admin_api_tokens` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `admin_id` BIGINT UNSIGNED NOT NULL COMMENT '服务账号ID',
+    `name` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '令牌用途',
+    `prefix` VARCHAR(20) NOT NULL DEFAULT '' COMMENT '令牌前缀，用于辨认',
+    `token_hash` CHAR(64) NOT NULL COMMENT '令牌的SHA-256',
+    `scopes` JSON NOT NULL COMMENT '令牌的权限码',
+    `signed` TINYINT NOT NULL DEFAULT 0 COMMENT '是否要求请求签名 0=否，1=是',
+    `signing_secret` VARBINARY(512) NULL COMMENT '请求签名密钥（KMS加密）',
+    `expires_at` DATETIME NOT NULL COMMENT '过期时间',
+    `last_used_at` DATETIME NULL COMMENT '最近使用时间',
+    `last_used_ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '最近使用IP',
+    `revoked_at` DATETIME NULL COMMENT '撤销时间',
+    `created_by` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建人ID',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    UNIQUE KEY `uk_token_hash` (`token_hash`) USING BTREE,
+    KEY `idx_admin_id` (`admin_id`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='服务账号API令牌表';
+
+-- 管理员登录记录表，只追加不修改
+CREATE TABLE IF NOT EXISTS `admin_login_logs` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `admin_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '管理员ID，账号不存在时为0',
+    `account` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '登录账号',
+    `success` TINYINT NOT NULL DEFAULT 0 COMMENT '是否成功: 1=成功 0=失败',
+    `reason` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '失败原因',
+    `ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '登录IP',
+    `user_agent` VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'User-Agent',
+    `mfa` VARCHAR(20) NOT NULL DEFAULT '' COMMENT '二次验证方式: totp/webauthn/recovery_code',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '登录时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `idx_admin_id_ip` (`admin_id`, `ip`) USING BTREE,
+    KEY `idx_account` (`account`) USING BTREE,
+    KEY `idx_created_at` (`created_at`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='管理员登录记录表';
+
+-- 登录IP白名单表，没有生效的记录时不限制登录IP
+CREATE TABLE IF NOT EXISTS `admin_ip_allowlist` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `cidr` VARCHAR(50) NOT NULL DEFAULT '' COMMENT 'IP网段(CIDR)',
+    `label` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '备注',
+    `expires_at` DATETIME NULL COMMENT '过期时间，NULL为永不过期',
+    `created_by` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建人ID',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='登录IP白名单表';
+
+-- 角色/管理员IP限制规则表，与全局白名单叠加生效，登录IP需同时满足全局、角色和管理员三级限制
+CREATE TABLE IF NOT EXISTS `admin_ip_rules` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `level` VARCHAR(10) NOT NULL DEFAULT '' COMMENT '规则级别: role/admin',
+    `target_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '角色ID或管理员ID',
+    `cidr` VARCHAR(50) NOT NULL DEFAULT '' COMMENT 'IP网段(CIDR)',
+    `label` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '备注',
+    `expires_at` DATETIME NULL COMMENT '过期时间，NULL为永不过期',
+    `created_by` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建人ID',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    `updated_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `idx_level_target` (`level`, `target_id`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='角色/管理员IP限制规则表';
+
+-- 管理操作审计日志表
+CREATE TABLE IF NOT EXISTS `admin_audit_logs` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `operator_id` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '操作者ID',
+    `operator_account` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '操作者账号',
+    `ip` VARCHAR(50) NOT NULL DEFAULT '' COMMENT '操作IP',
+    `action` VARCHAR(40) NOT NULL DEFAULT '' COMMENT '操作类型',
+    `target` VARCHAR(100) NOT NULL DEFAULT '' COMMENT '操作对象',
+    `detail` JSON NULL COMMENT '操作详情',
+    `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+    PRIMARY KEY (`id`) USING BTREE,
+    KEY `idx_operator_id` (`operator_id`) USING BTREE,
+    KEY `idx_action` (`action`) USING BTREE,
+    KEY `idx_created_at` (`created_at`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='管理操作审计日志表';
+
+-- 角色权限模板表，管理员的有效权限 = (角色权限 ∪ 额外授予) − 排除
+-- 权限码以.分隔层级，可使用通配符如 member.* 或 *，排除优先；旧版权限码(如 member-list)读取时自动转换
+CREATE TABLE IF NOT EXISTS `role_perms` (
+    `id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `role_id` INT UNSIGNED NOT NULL COMMENT '角色ID',
+    `perms` JSON NOT NULL COMMENT '权限列表JSON数组，支持通配符',
+    `created_at` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '创建时间戳',
+    `updated_at` BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '更新时间戳',
+    PRIMARY KEY (`id`) USING BTREE,
+    UNIQUE KEY `idx_role_id` (`role_id`) USING BTREE
+) ENGINE=INNODB AUTO_INCREMENT=1 DEFAULT CHARSET=utf8mb4 COMMENT='角色权限模板表';
+
+-- 启用密码有效期之前的账号没有密码修改时间，从升级时开始计算有效期
+UPDATE `admins` SET `pwd_changed_at` = NOW() WHERE `pwd_changed_at` IS NULL;
+
+-- 旧版登录IP白名单(system_conf.ip_whitelist)在admin_ip_allowlist没有任何记录时仍然生效，
+-- 请在后台把其中的IP或网段添加到白名单后，删除该配置
+
+-- 以下接口原先只要登录即可访问，改为校验权限码后按角色授予：
+-- 代理审核(agent.review.*)授予全部默认角色，保持原有访问范围；
+-- 创建管理员、查看/修改管理员权限和查看角色权限只授予admin角色，operation、support、finance角色不再能访问这些接口
+-- 执行后清除Redis中的admin.perms:*权限缓存，或等待缓存过期（1小时）后生效
+INSERT INTO `role_perms` (`role_id`, `perms`, `created_at`, `updated_at`) VALUES
+(2, '["admin.create", "admin.perm.view", "admin.perm.edit", "role.perm.view", "agent.review.*"]', UNIX_TIMESTAMP(), UNIX_TIMESTAMP()),
+(3, '["agent.review.*"]', UNIX_TIMESTAMP(), UNIX_TIMESTAMP()),
+(4, '["agent.review.*"]', UNIX_TIMESTAMP(), UNIX_TIMESTAMP()),
+(5, '["agent.review.*"]', UNIX_TIMESTAMP(), UNIX_TIMESTAMP())
+ON DUPLICATE KEY UPDATE `perms` = JSON_MERGE_PRESERVE(`perms`, VALUES(`perms`)), `updated_at` = VALUES(`updated_at`);
+
+-- 系统配置新增项（system库），已存在的配置保留原值
+INSERT IGNORE INTO `system`.`system_conf` (`key`, `val`, `desc`, `show`, `edit`) VALUES
+('admin_max_sessions', '5', '管理员最大并发会话数(0不限)', 1, 1),
+('session_idle_timeout', '259200', '会话空闲超时(秒)', 1, 1),
+('session_max_lifetime', '604800', '会话最长有效期(秒)', 1, 1),
+('session_bind_mode', '0', '会话绑定 0关闭 1仅记录日志 2拒绝', 1, 1),
+('session_bind_same_subnet', '1', '会话绑定允许同/24网段换IP', 1, 1),
+('login_max_failures', '5', '登录连续失败锁定次数', 1, 1),
+('login_ip_max_failures', '20', '单IP登录失败锁定次数', 1, 1),
+('login_lock_minutes', '15', '登录锁定时长(分钟)', 1, 1),
+('login_delay_after', '2', '连续失败几次后开始延迟', 1, 1),
+('pwd_min_length', '8', '密码最小长度', 1, 1),
+('pwd_min_classes', '3', '密码至少包含的字符类别数', 1, 1),
+('pwd_banned', 'Password1!,Admin@123,Aa123456,Qwer1234!', '禁用密码(逗号分隔)', 1, 1),
+('pwd_expire_days', '90', '密码有效期(天，0不过期)', 1, 1),
+('pwd_expire_grace_days', '7', '密码过期后的修改宽限期(天)', 1, 1),
+('pwd_history_count', '5', '不可重复使用的历史密码数', 1, 1),
+('totp_skew', '1', '谷歌验证码允许的时钟偏差(周期数)', 1, 1),
+('mfa_required_roles', '1', '必须绑定谷歌验证器的角色ID(逗号分隔)', 1, 1),
+('mfa_enroll_grace_hours', '72', '新账号绑定谷歌验证器宽限期(小时)', 1, 1),
+('mfa_enroll_token_hours', '24', '谷歌验证器重新绑定令牌有效期(小时)', 1, 1),
+('webauthn_rp_id', '', 'WebAuthn依赖方ID(后台域名，空为关闭)', 1, 1),
+('webauthn_rp_name', 'admin', 'WebAuthn依赖方名称', 1, 1),
+('webauthn_origins', '', 'WebAuthn允许的来源(逗号分隔)', 1, 1),
+('stepup_minutes', '5', '重新验证身份后敏感操作有效期(分钟)', 1, 1),
+('login_alert_failures', '3', '连续登录失败达到该次数时发送告警(0为不告警)', 1, 1),
+('api_token_max_days', '90', '服务账号API令牌最长有效期(天)', 1, 1),
+('api_signature_skew_seconds', '300', 'API签名请求允许的时间偏差(秒)', 1, 1);
@@ -9,3 +9,33 @@ CREATE TABLE `system`.`system_conf` (
     PRIMARY KEY (`id`) USING BTREE,
     UNIQUE `idx_key` (`key`) USING BTREE
 ) ENGINE=INNODB AUTO_INCREMENT=10 DEFAULT CHARSET=utf8mb4 COMMENT='系统全局配置';
+
+INSERT INTO `system`.`system_conf` (`key`, `val`, `desc`, `show`, `edit`) VALUES
+('admin_max_sessions', '5', '管理员最大并发会话数(0不限)', 1, 1),
+('session_idle_timeout', '259200', '会话空闲超时(秒)', 1, 1),
+('session_max_lifetime', '604800', '会话最长有效期(秒)', 1, 1),
+('session_bind_mode', '0', '会话绑定 0关闭 1仅记录日志 2拒绝', 1, 1),
+('session_bind_same_subnet', '1', '会话绑定允许同/24网段换IP', 1, 1),
+('login_max_failures', '5', '登录连续失败锁定次数', 1, 1),
+('login_ip_max_failures', '20', '单IP登录失败锁定次数', 1, 1),
+('login_lock_minutes', '15', '登录锁定时长(分钟)', 1, 1),
+('login_delay_after', '2', '连续失败几次后开始延迟', 1, 1),
+('pwd_min_length', '8', '密码最小长度', 1, 1),
+('pwd_min_classes', '3', '密码至少包含的字符类别数', 1, 1),
+('pwd_banned', 'Password1!,Admin@123,Aa123456,Qwer1234!', '禁用密码(逗号分隔)', 1, 1),
+('pwd_expire_days', '90', '密码有效期(天，0不过期)', 1, 1),
+('pwd_expire_grace_days', '7', '密码过期后的修改宽限期(天)', 1, 1),
+('pwd_history_count', '5', '不可重复使用的历史密码数', 1, 1),
+('totp_skew', '1', '谷歌验证码允许的时钟偏差(周期数)', 1, 1),
+('mfa_required_roles', '1', '必须绑定谷歌验证器的角色ID(逗号分隔)', 1, 1),
+('mfa_enroll_grace_hours', '72', '新账号绑定谷歌验证器宽限期(小时)', 1, 1),
+('mfa_enroll_token_hours', '24', '谷歌验证器重新绑定令牌有效期(小时)', 1, 1),
+('webauthn_rp_id', '', 'WebAuthn依赖方ID(后台域名，空为关闭)', 1, 1),
+('webauthn_rp_name', 'admin', 'WebAuthn依赖方名称', 1, 1),
+('webauthn_origins', '', 'WebAuthn允许的来源(逗号分隔)', 1, 1),
+('stepup_minutes', '5', '重新验证身份后敏感操作有效期(分钟)', 1, 1),
+('login_alert_failures', '3', '连续登录失败达到该次数时发送告警(0为不告警)', 1, 1),
+('api_token_max_days', '90', '服务账号API令牌最长有效期(天)', 1, 1),
+('api_signature_skew_seconds', '300', 'API签名请求允许的时间偏差(秒)', 1, 1);
+-- 按角色覆盖: 在key后加 _角色ID，如 finance 角色
+-- INSERT INTO `system`.`system_conf` (`key`, `val`, `desc`, `show`, `edit`) VALUES ('session_idle_timeout_5', '1800', '财务会话空闲超时(秒)', 1, 1);